@@ -0,0 +1,46 @@
+/*
+Copyright © 2024 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	. "github.com/patrixr/glue/pkg/runner"
+	"github.com/spf13/cobra"
+)
+
+var applyCmd = &cobra.Command{
+	Use:   "apply <plan.json>",
+	Short: "Apply a previously saved blueprint",
+	Long:  `Apply a blueprint saved with 'glue --plan --out plan.json', without requiring the original Lua scripts`,
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		verbose, _ := cmd.Flags().GetBool("verbose")
+		rollback, _ := cmd.Flags().GetString("rollback-on-failure")
+		failFast, _ := cmd.Flags().GetBool("fail-fast")
+		strictOwnership, _ := cmd.Flags().GetBool("strict-ownership")
+		step, _ := cmd.Flags().GetString("step")
+		jobs, _ := cmd.Flags().GetInt("jobs")
+
+		RunGlueApply(ApplyOptions{
+			Verbose:         verbose,
+			File:            args[0],
+			Rollback:        rollback,
+			FailFast:        failFast,
+			StrictOwnership: strictOwnership,
+			Step:            step,
+			Jobs:            jobs,
+		})
+	},
+}
+
+func init() {
+	applyCmd.Flags().BoolP("verbose", "v", false, "Enable verbose mode")
+	applyCmd.Flags().String("rollback-on-failure", "", "Undo the changes of the failing groups (group) or of the whole run (run)")
+	applyCmd.Flags().Lookup("rollback-on-failure").NoOptDefVal = "group"
+	applyCmd.Flags().Bool("fail-fast", false, "Stop the run after the first failing action")
+	applyCmd.Flags().Bool("strict-ownership", false, "Fail when several groups manage the same file or package, instead of warning")
+	applyCmd.Flags().String("step", "", "Only run the action with the given ID, as listed by --plan")
+	applyCmd.Flags().IntP("jobs", "j", 0, "Maximum number of actions to run concurrently within parallel groups, defaults to the saved plan")
+
+	rootCmd.AddCommand(applyCmd)
+}
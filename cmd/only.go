@@ -14,14 +14,40 @@ var onlyCmd = &cobra.Command{
 	Long:  `Run Glue on a single part of the configuration using a selector`,
 	Run: func(cmd *cobra.Command, args []string) {
 		planOnly, _ := cmd.Flags().GetBool("plan")
+		format, _ := cmd.Flags().GetString("format")
+		check, _ := cmd.Flags().GetBool("check")
+		diff, _ := cmd.Flags().GetBool("diff")
+		interactive, _ := cmd.Flags().GetBool("interactive")
 		verbose, _ := cmd.Flags().GetBool("verbose")
+		rollback, _ := cmd.Flags().GetString("rollback-on-failure")
+		failFast, _ := cmd.Flags().GetBool("fail-fast")
+		strictOwnership, _ := cmd.Flags().GetBool("strict-ownership")
+		step, _ := cmd.Flags().GetString("step")
+		resume, _ := cmd.Flags().GetBool("resume")
+		rerunFailed, _ := cmd.Flags().GetBool("rerun-failed")
 		path, _ := cmd.Flags().GetString("path")
+		out, _ := cmd.Flags().GetString("out")
+		jobs, _ := cmd.Flags().GetInt("jobs")
+		noDeps, _ := cmd.Flags().GetBool("no-deps")
 
 		RunGlue(RunOptions{
-			PlanOnly: planOnly,
-			Verbose:  verbose,
-			Path:     path,
-			Selector: args[0],
+			PlanOnly:        planOnly,
+			Format:          format,
+			Check:           check,
+			Diff:            diff,
+			Interactive:     interactive,
+			Rollback:        rollback,
+			FailFast:        failFast,
+			StrictOwnership: strictOwnership,
+			Step:            step,
+			Resume:          resume,
+			RerunFailed:     rerunFailed,
+			Verbose:         verbose,
+			Path:            path,
+			Out:             out,
+			Jobs:            jobs,
+			NoDeps:          noDeps,
+			Selector:        args[0],
 		})
 	},
 }
@@ -30,6 +56,20 @@ func init() {
 	onlyCmd.Flags().String("path", "", "Directory or file to look for glue.lua")
 	onlyCmd.Flags().BoolP("verbose", "v", false, "Enable verbose mode")
 	onlyCmd.Flags().Bool("plan", false, "See the execution blueprints without applying anything")
+	onlyCmd.Flags().String("format", "tree", "Format of the plan printed by --plan: tree, json, mermaid, dot or sh")
+	onlyCmd.Flags().Bool("check", false, "Report which actions would change the system without applying anything")
+	onlyCmd.Flags().Bool("diff", false, "Preview the changes to files as unified diffs, implies --check")
+	onlyCmd.Flags().Bool("interactive", false, "Ask for confirmation before running each action")
+	onlyCmd.Flags().String("rollback-on-failure", "", "Undo the changes of the failing groups (group) or of the whole run (run)")
+	onlyCmd.Flags().Lookup("rollback-on-failure").NoOptDefVal = "group"
+	onlyCmd.Flags().Bool("fail-fast", false, "Stop the run after the first failing action")
+	onlyCmd.Flags().Bool("strict-ownership", false, "Fail when several groups manage the same file or package, instead of warning")
+	onlyCmd.Flags().String("step", "", "Only run the action with the given ID, as listed by --plan")
+	onlyCmd.Flags().Bool("resume", false, "Continue the previous run of the plan from its first failed action")
+	onlyCmd.Flags().Bool("rerun-failed", false, "Only run the actions which failed during the previous run of the plan")
+	onlyCmd.Flags().String("out", "", "Save the compiled blueprint to a JSON file")
+	onlyCmd.Flags().Bool("no-deps", false, "Do not include the groups the selected groups depend on")
+	onlyCmd.Flags().IntP("jobs", "j", 0, "Maximum number of actions to run concurrently within parallel groups")
 
 	rootCmd.AddCommand(onlyCmd)
 }
@@ -0,0 +1,41 @@
+/*
+Copyright © 2024 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	. "github.com/patrixr/glue/pkg/runner"
+	"github.com/spf13/cobra"
+)
+
+var planCmd = &cobra.Command{
+	Use:   "plan",
+	Short: "Print the compiled blueprint, or compare it to another one",
+	Long: `Print the compiled blueprint without applying anything.
+
+With --compare, the plan is compared to one saved with 'glue --plan --format json' or 'glue --plan --out'.
+With --rev, it is compared to the plan of a git revision of the scripts, or the plans of two revisions are compared.
+Groups and actions added, removed or modified are listed, along with the arguments which changed`,
+	Run: func(cmd *cobra.Command, args []string) {
+		path, _ := cmd.Flags().GetString("path")
+		format, _ := cmd.Flags().GetString("format")
+		compare, _ := cmd.Flags().GetString("compare")
+		revisions, _ := cmd.Flags().GetStringArray("rev")
+
+		RunGluePlan(PlanOptions{
+			Path:      path,
+			Format:    format,
+			Compare:   compare,
+			Revisions: revisions,
+		})
+	},
+}
+
+func init() {
+	planCmd.Flags().String("path", "", "Directory or file to look for glue.lua")
+	planCmd.Flags().String("format", "tree", "Format of the output: tree, json, mermaid, dot or sh. Comparisons are printed as tree or json")
+	planCmd.Flags().String("compare", "", "Compare the plan to a plan saved as JSON")
+	planCmd.Flags().StringArray("rev", nil, "Compare the plan to the one of a git revision, twice to compare two revisions")
+
+	rootCmd.AddCommand(planCmd)
+}
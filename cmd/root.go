@@ -16,13 +16,37 @@ var rootCmd = &cobra.Command{
 	Long:  `Glue is a machine configuration tool that allows you to use Lua to easily streamline your system setup`,
 	Run: func(cmd *cobra.Command, args []string) {
 		planOnly, _ := cmd.Flags().GetBool("plan")
+		format, _ := cmd.Flags().GetString("format")
+		check, _ := cmd.Flags().GetBool("check")
+		diff, _ := cmd.Flags().GetBool("diff")
+		interactive, _ := cmd.Flags().GetBool("interactive")
 		verbose, _ := cmd.Flags().GetBool("verbose")
+		rollback, _ := cmd.Flags().GetString("rollback-on-failure")
+		failFast, _ := cmd.Flags().GetBool("fail-fast")
+		strictOwnership, _ := cmd.Flags().GetBool("strict-ownership")
+		step, _ := cmd.Flags().GetString("step")
+		resume, _ := cmd.Flags().GetBool("resume")
+		rerunFailed, _ := cmd.Flags().GetBool("rerun-failed")
 		path, _ := cmd.Flags().GetString("path")
+		out, _ := cmd.Flags().GetString("out")
+		jobs, _ := cmd.Flags().GetInt("jobs")
 
 		RunGlue(RunOptions{
-			PlanOnly: planOnly,
-			Verbose:  verbose,
-			Path:     path,
+			PlanOnly:        planOnly,
+			Format:          format,
+			Check:           check,
+			Diff:            diff,
+			Interactive:     interactive,
+			Rollback:        rollback,
+			FailFast:        failFast,
+			StrictOwnership: strictOwnership,
+			Step:            step,
+			Resume:          resume,
+			RerunFailed:     rerunFailed,
+			Verbose:         verbose,
+			Path:            path,
+			Out:             out,
+			Jobs:            jobs,
 		})
 	},
 }
@@ -38,4 +62,17 @@ func init() {
 	rootCmd.Flags().String("path", "", "Directory or file to look for glue.lua")
 	rootCmd.Flags().BoolP("verbose", "v", false, "Enable verbose mode")
 	rootCmd.Flags().Bool("plan", false, "See the execution blueprints without applying anything")
+	rootCmd.Flags().String("format", "tree", "Format of the plan printed by --plan: tree, json, mermaid, dot or sh")
+	rootCmd.Flags().Bool("check", false, "Report which actions would change the system without applying anything")
+	rootCmd.Flags().Bool("diff", false, "Preview the changes to files as unified diffs, implies --check")
+	rootCmd.Flags().Bool("interactive", false, "Ask for confirmation before running each action")
+	rootCmd.Flags().String("rollback-on-failure", "", "Undo the changes of the failing groups (group) or of the whole run (run)")
+	rootCmd.Flags().Lookup("rollback-on-failure").NoOptDefVal = "group"
+	rootCmd.Flags().Bool("fail-fast", false, "Stop the run after the first failing action")
+	rootCmd.Flags().Bool("strict-ownership", false, "Fail when several groups manage the same file or package, instead of warning")
+	rootCmd.Flags().String("step", "", "Only run the action with the given ID, as listed by --plan")
+	rootCmd.Flags().Bool("resume", false, "Continue the previous run of the plan from its first failed action")
+	rootCmd.Flags().Bool("rerun-failed", false, "Only run the actions which failed during the previous run of the plan")
+	rootCmd.Flags().String("out", "", "Save the compiled blueprint to a JSON file")
+	rootCmd.Flags().IntP("jobs", "j", 0, "Maximum number of actions to run concurrently within parallel groups")
 }
@@ -0,0 +1,10 @@
+group("downloads", { parallel = true }, function()
+    Sh("sleep 1")
+    Sh("sleep 1")
+    Sh("sleep 1")
+end)
+
+group("sequential", function()
+    Sh("echo first")
+    Sh("echo second")
+end)
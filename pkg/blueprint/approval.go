@@ -0,0 +1,27 @@
+package blueprint
+
+import "context"
+
+// Asks whether an action should run, right before it runs
+// Declined actions are reported as skipped, with the returned reason
+type ApproveFunc func(ctx context.Context, action Node) (approved bool, reason string)
+
+// (internal)
+type approvalKey struct{}
+
+// WithApproval returns a context in which every action has to be approved before running
+// Checks are not subject to approval, as they have no side effects
+func WithApproval(ctx context.Context, approve ApproveFunc) context.Context {
+	return context.WithValue(ctx, approvalKey{}, approve)
+}
+
+// (internal)
+func (blueprint *SerialBlueprint) approved(ctx context.Context) (bool, string) {
+	approve, ok := ctx.Value(approvalKey{}).(ApproveFunc)
+
+	if !ok || approve == nil {
+		return true, ""
+	}
+
+	return approve(ctx, blueprint.Info())
+}
@@ -0,0 +1,38 @@
+package blueprint_test
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/patrixr/glue/pkg/blueprint"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApproval(t *testing.T) {
+	ran := []string{}
+	record := func(name string) ActionFunc {
+		return func(ctx context.Context, trace *Trace) error {
+			ran = append(ran, name)
+			return nil
+		}
+	}
+
+	plan := NewSerialBlueprint("root")
+	plan.Action(ActionDef{Name: "install", Fn: record("install")})
+	plan.Action(ActionDef{Name: "upgrade", Fn: record("upgrade")})
+
+	ctx := WithApproval(context.Background(), func(ctx context.Context, action Node) (bool, string) {
+		return action.Name != "upgrade", "Declined"
+	})
+
+	results := plan.Execute(ctx)
+
+	assert.True(t, results.Success)
+	assert.Equal(t, []string{"install"}, ran)
+	assert.Equal(t, StatusSkipped, results.Traces[1].Status)
+	assert.Equal(t, "Declined", results.Traces[1].Details)
+
+	// checks are never subject to approval
+	plan.Check(ctx)
+	assert.Equal(t, []string{"install"}, ran)
+}
@@ -0,0 +1,101 @@
+package blueprint
+
+// ReplaceNative returns a copy of native data in which fn replaced values, at any depth
+// fn reports whether it replaced the value, the items of the lists and dicts it didn't replace are visited
+func ReplaceNative(val any, fn func(val any) (any, bool, error)) (any, error) {
+	replaced, ok, err := fn(val)
+
+	if err != nil || ok {
+		return replaced, err
+	}
+
+	switch data := val.(type) {
+	case []any:
+		list := make([]any, len(data))
+
+		for i, item := range data {
+			resolved, err := ReplaceNative(item, fn)
+
+			if err != nil {
+				return nil, err
+			}
+
+			list[i] = resolved
+		}
+
+		return list, nil
+	case map[string]any:
+		dict := make(map[string]any, len(data))
+
+		for key, item := range data {
+			resolved, err := ReplaceNative(item, fn)
+
+			if err != nil {
+				return nil, err
+			}
+
+			dict[key] = resolved
+		}
+
+		return dict, nil
+	}
+
+	return val, nil
+}
+
+// ContainsNative reports whether native data holds a value matching the predicate, at any depth
+func ContainsNative(val any, match func(val any) bool) bool {
+	if match(val) {
+		return true
+	}
+
+	switch data := val.(type) {
+	case []any:
+		for _, item := range data {
+			if ContainsNative(item, match) {
+				return true
+			}
+		}
+	case map[string]any:
+		for _, item := range data {
+			if ContainsNative(item, match) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// A module argument evaluated by the script right before the action runs
+// Only its name is stored in the blueprint, which shows the argument as pending until then
+type Pending struct {
+	Name string
+}
+
+// (internal)
+const pendingKey = "$pending"
+
+// Native returns the pending argument as native data, as stored in the arguments of actions
+func (pending Pending) Native() map[string]any {
+	return map[string]any{pendingKey: pending.Name}
+}
+
+// AsPending returns the pending argument held by native data, if any
+func AsPending(val any) (Pending, bool) {
+	dict, ok := val.(map[string]any)
+
+	if !ok || len(dict) != 1 {
+		return Pending{}, false
+	}
+
+	name, ok := dict[pendingKey].(string)
+
+	return Pending{Name: name}, ok
+}
+
+// (internal)
+func isPending(val any) bool {
+	_, ok := AsPending(val)
+	return ok
+}
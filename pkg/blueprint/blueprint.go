@@ -8,16 +8,126 @@ package blueprint
 // Not only do they allow for the execution of complex workflows, the also enable us to view, serialize and share those
 // workflows with or without executing them.
 //
+// Every action of a blueprint is stored as data: the name of the module to run and the arguments it was called with.
+// A compiled blueprint can therefore be saved to a JSON file and applied later, or on a different machine, without the original Lua scripts.
+//
+// ```bash
+// glue --plan --out plan.json
+// glue apply plan.json
+// ```
+//
+// A plan can also be exported as a self-contained POSIX shell script, applied on machines where glue isn't installed.
+// Every module used by the plan must support it, as well as its conditions, which have to be shell tests:
+//
+// ```bash
+// glue --plan --format sh > setup.sh
+// ```
+
+import (
+	"context"
+	"time"
+)
+
+// Runs an action, the context is cancelled when the action times out or the run is interrupted
+type ActionFunc func(ctx context.Context, trace *Trace) error
 
-type ActionFunc func() error
+type BlueprintFunc func(ctx context.Context) Trace
+
+// Rebuilds the executable functions of an action from its module name and arguments
+// Groups with a condition are bound as well, as actions without a module
+type ActionBinder func(action ActionDef) (ActionDef, error)
+
+type ActionDef struct {
+	Name       string
+	Details    string
+	Annotation string
+	Group      string
+	Module     string
+	Args       []any
+	Fn         ActionFunc
+	// Reports whether the action would change anything, without side effects (optional)
+	Check ActionFunc
+	// Renders the action as shell commands, see ExportScript (optional)
+	Script ScriptFunc
+	// Attempts the action again when it fails (optional)
+	Retry *RetryPolicy
+	// Names of the handlers to run if the action changes anything
+	Notify []string
+	// Skips the action when it doesn't hold (optional)
+	When *Condition
+	// Failures of the action are reported but don't fail the run
+	IgnoreErrors bool
+	// Cancels the action when it runs for longer, e.g. "10m" (optional)
+	Timeout string
+	// The phase the action runs in, see SplitPhases (optional)
+	Phase Phase
+	// The name the result of the action is registered under, see ResultRef (optional)
+	Register string
+	// The location of the script code declaring the action (optional)
+	Source string
+	// The directory of the script declaring the action, relative paths of its arguments are resolved from it (optional)
+	Dir string
+	// The calls of other groups merged into the action, see core.Coalesce (optional)
+	Merged []MergedCall
+}
+
+// A module call merged into the action of another group
+type MergedCall struct {
+	Group string `json:"group"`
+	// The arguments of the call, e.g `packages: neovim, ripgrep`
+	Summary string `json:"summary"`
+}
 
-type BlueprintFunc func() Trace
+// The outcome of an action
+type Status string
+
+const (
+	// The action ran and the machine was already in the desired state
+	StatusOk Status = "ok"
+	// The action ran and modified the machine (or would, when checking)
+	StatusChanged Status = "changed"
+	// The action returned an error
+	StatusFailed Status = "failed"
+	// The action did not run
+	StatusSkipped Status = "skipped"
+)
 
 type Trace struct {
-	Name       string `json:"name"`
-	Details    string `json:"details"`
-	Error      error  `json:"error"`
-	Annotation string `json:"annotation"`
+	// Identifies the action, see Resolve
+	ID         string        `json:"id,omitempty"`
+	Name       string        `json:"name"`
+	Details    string        `json:"details"`
+	Group      string        `json:"group"`
+	Error      error         `json:"error"`
+	Annotation string        `json:"annotation"`
+	Changed    bool          `json:"changed"`
+	Status     Status        `json:"status"`
+	StartTime  time.Time     `json:"start_time"`
+	EndTime    time.Time     `json:"end_time"`
+	Duration   time.Duration `json:"duration"`
+	// Unified diff of the files the action modifies, when diffs are enabled
+	Diff string `json:"diff"`
+	// Reverts the changes made by the action, registered by modules which support it
+	Undo          func() error `json:"-"`
+	RolledBack    bool         `json:"rolled_back"`
+	RollbackError error        `json:"rollback_error"`
+	// Every run of the action, when it has a retry policy
+	Attempts []Attempt `json:"attempts,omitempty"`
+	Notify   []string  `json:"notify,omitempty"`
+	// The action failed, but its errors are ignored
+	Ignored bool `json:"ignored,omitempty"`
+	// The action never ran because its group, or the run, was stopped
+	Cancelled bool `json:"cancelled,omitempty"`
+	// The position of the action in execution order, 0 for traces of groups
+	Step int `json:"step,omitempty"`
+	// The calls of every group the action ran on behalf of, when several calls were coalesced
+	Merged []MergedCall `json:"merged,omitempty"`
+}
+
+// Skip marks the action as not having run, the reason is kept in the trace details
+func (trace *Trace) Skip(reason string) {
+	trace.Status = StatusSkipped
+	trace.Details = reason
 }
 
 type Results struct {
@@ -25,11 +135,51 @@ type Results struct {
 	Success        bool    `json:"success"`
 	ErrorCount     int     `json:"error_count"`
 	TimeElapsedSec int     `json:"time_elapsed"`
+	// An error policy or an interruption stopped the run, the remaining actions are cancelled
+	Stopped bool `json:"stopped"`
+}
+
+// Count returns the number of traces with the given status
+func (results *Results) Count(status Status) int {
+	count := 0
+	for _, trace := range results.Traces {
+		if trace.Status == status {
+			count++
+		}
+	}
+	return count
+}
+
+// WouldChangeCount returns the number of actions reported as changed by a check
+func (results *Results) WouldChangeCount() int {
+	count := 0
+	for _, trace := range results.Traces {
+		if trace.Changed {
+			count++
+		}
+	}
+	return count
+}
+
+// Merge appends the traces and errors of other to the results
+func (results *Results) Merge(other Results) {
+	results.Traces = append(results.Traces, other.Traces...)
+	results.ErrorCount += other.ErrorCount
+	results.Success = results.Success && other.Success
+	results.Stopped = results.Stopped || other.Stopped
 }
 
 type Blueprint interface {
-	Execute() Results
-	Action(name string, details string, usertext string, fn ActionFunc)
+	Execute(ctx context.Context) Results
+	Check(ctx context.Context) Results
+	Action(action ActionDef)
 	Add(blueprint Blueprint)
+	AddHandler(handler Blueprint)
+	Bind(binder ActionBinder) error
 	PrettyPrint() string
+	// Metadata of the node, see Walk
+	Info() Node
+	// The children of the node followed by its handlers
+	Nested() []Blueprint
+	SetNested(nested []Blueprint)
 }
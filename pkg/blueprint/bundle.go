@@ -0,0 +1,102 @@
+package blueprint
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+)
+
+const BundleVersion = 1
+
+// A portable, serialized version of a compiled blueprint
+type Bundle struct {
+	Version   int       `json:"version"`
+	Script    string    `json:"script"`
+	Blueprint Blueprint `json:"blueprint"`
+}
+
+// Save writes the blueprint to the writer as a JSON bundle
+// Plans with pending arguments cannot be saved, as they are evaluated by the script
+// The script is recorded as an absolute path, so the bundle can be applied from any directory
+func Save(blueprint Blueprint, script string, w io.Writer) error {
+	pending, found := Find(blueprint, func(node Node) bool {
+		return ContainsNative(node.Args, isPending)
+	})
+
+	if found {
+		return fmt.Errorf("Plans with deferred arguments cannot be saved, the arguments of %s [%s] are evaluated by the script when it runs", pending.Name, pending.ID)
+	}
+
+	script, err := filepath.Abs(script)
+
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.SetEscapeHTML(false)
+
+	return encoder.Encode(Bundle{
+		Version:   BundleVersion,
+		Script:    script,
+		Blueprint: blueprint,
+	})
+}
+
+// Load reads a JSON bundle and rebuilds the blueprint it contains
+// The actions of the returned blueprint are bound using the binder
+// Actions saved without a directory are resolved from the directory of the bundle's script
+func Load(r io.Reader, binder ActionBinder) (Blueprint, error) {
+	var bundle struct {
+		Version   int             `json:"version"`
+		Script    string          `json:"script"`
+		Blueprint json.RawMessage `json:"blueprint"`
+	}
+
+	if err := json.NewDecoder(r).Decode(&bundle); err != nil {
+		return nil, err
+	}
+
+	if bundle.Version != BundleVersion {
+		return nil, fmt.Errorf("Unsupported blueprint bundle version %d", bundle.Version)
+	}
+
+	if len(bundle.Blueprint) == 0 {
+		return nil, errors.New("Bundle does not contain a blueprint")
+	}
+
+	blueprint, err := decodeBlueprint(bundle.Blueprint)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// a bundle may have been compiled with a selector, some dependencies can be absent
+	if err := Resolve(blueprint, true); err != nil {
+		return nil, err
+	}
+
+	if err := blueprint.Bind(scriptBinder(binder, bundle.Script)); err != nil {
+		return nil, err
+	}
+
+	return blueprint, nil
+}
+
+// (internal)
+// Defaults the directory of the actions to the one of the script they were compiled from
+func scriptBinder(binder ActionBinder, script string) ActionBinder {
+	if !filepath.IsAbs(script) {
+		return binder
+	}
+
+	return func(action ActionDef) (ActionDef, error) {
+		if len(action.Dir) == 0 {
+			action.Dir = filepath.Dir(script)
+		}
+		return binder(action)
+	}
+}
@@ -0,0 +1,83 @@
+package blueprint_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"testing"
+
+	. "github.com/patrixr/glue/pkg/blueprint"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBundle(t *testing.T) {
+	plan := NewSerialBlueprint("<root>")
+	group := NewSerialBlueprint("group")
+
+	group.Action(ActionDef{
+		Name:   "Copy",
+		Module: "Copy",
+		Args:   []any{map[string]any{"source": "a", "dest": "b"}},
+		Fn:     func(ctx context.Context, trace *Trace) error { return nil },
+	})
+
+	plan.Add(group)
+
+	plan.Action(ActionDef{
+		Name:   "Sh",
+		Module: "Sh",
+		Args:   []any{"echo hello"},
+		Fn:     func(ctx context.Context, trace *Trace) error { return nil },
+	})
+
+	var buf bytes.Buffer
+
+	assert.NoError(t, Resolve(plan, false))
+	assert.NoError(t, Save(plan, "glue.lua", &buf))
+
+	t.Run("actions are rebuilt from their module and arguments", func(t *testing.T) {
+		calls := []string{}
+
+		loaded, err := Load(bytes.NewReader(buf.Bytes()), func(action ActionDef) (ActionDef, error) {
+			action.Fn = func(ctx context.Context, trace *Trace) error {
+				calls = append(calls, action.Module)
+				return nil
+			}
+			return action, nil
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, plan.PrettyPrint(), loaded.PrettyPrint())
+
+		copyAction := loaded.(*SerialBlueprint).Children[0].(*SerialBlueprint).Children[0].(*SerialBlueprint)
+		assert.Equal(t, []any{map[string]any{"source": "a", "dest": "b"}}, copyAction.Args)
+
+		results := loaded.Execute(context.Background())
+
+		assert.Equal(t, 0, results.ErrorCount)
+		assert.Equal(t, []string{"Copy", "Sh"}, calls)
+	})
+
+	t.Run("actions without a directory are resolved from the script", func(t *testing.T) {
+		dirs := []string{}
+
+		_, err := Load(bytes.NewReader(buf.Bytes()), func(action ActionDef) (ActionDef, error) {
+			dirs = append(dirs, action.Dir)
+			return action, nil
+		})
+
+		cwd, _ := os.Getwd()
+
+		assert.NoError(t, err)
+		assert.Equal(t, []string{cwd, cwd}, dirs, "the script is saved as an absolute path")
+	})
+
+	t.Run("loading fails if an action cannot be bound", func(t *testing.T) {
+		_, err := Load(bytes.NewReader(buf.Bytes()), func(action ActionDef) (ActionDef, error) {
+			return action, errors.New("Unknown module")
+		})
+
+		assert.Error(t, err)
+	})
+}
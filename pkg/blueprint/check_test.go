@@ -0,0 +1,55 @@
+package blueprint_test
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/patrixr/glue/pkg/blueprint"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheck(t *testing.T) {
+	executed := false
+
+	plan := NewSerialBlueprint("root")
+
+	plan.Action(ActionDef{
+		Name: "up-to-date",
+		Fn: func(ctx context.Context, trace *Trace) error {
+			executed = true
+			return nil
+		},
+		Check: func(ctx context.Context, trace *Trace) error {
+			return nil
+		},
+	})
+
+	plan.Action(ActionDef{
+		Name: "outdated",
+		Fn: func(ctx context.Context, trace *Trace) error {
+			executed = true
+			return nil
+		},
+		Check: func(ctx context.Context, trace *Trace) error {
+			trace.Changed = true
+			return nil
+		},
+	})
+
+	plan.Action(ActionDef{
+		Name: "unchecked",
+		Fn: func(ctx context.Context, trace *Trace) error {
+			executed = true
+			return nil
+		},
+	})
+
+	results := plan.Check(context.Background())
+
+	assert.False(t, executed, "check mode should not run actions")
+	assert.Len(t, results.Traces, 3)
+	assert.False(t, results.Traces[0].Changed)
+	assert.True(t, results.Traces[1].Changed)
+	assert.True(t, results.Traces[2].Changed, "actions without a check are assumed to change")
+	assert.Equal(t, 2, results.WouldChangeCount())
+}
@@ -0,0 +1,114 @@
+package blueprint
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// A condition evaluated right before an action or a group runs
+// When it doesn't hold, the action (or every action of the group) is skipped
+type Condition struct {
+	// A shell command, the condition holds if it exits successfully
+	Shell string `json:"shell,omitempty"`
+	// A field of the result of a registered action, see Result.Holds
+	Result *ResultRef `json:"result,omitempty"`
+	// Evaluates the condition, set by the binder for shell commands and by the runtime for functions
+	Eval func(ctx context.Context) (bool, error) `json:"-"`
+}
+
+func (condition *Condition) MarshalJSON() ([]byte, error) {
+	if len(condition.Shell) == 0 && condition.Result == nil {
+		return nil, errors.New("Conditions defined as functions cannot be saved, use a shell test instead")
+	}
+
+	type fields Condition
+
+	return json.Marshal((*fields)(condition))
+}
+
+// (internal)
+func (condition *Condition) String() string {
+	if condition.Shell == "" && condition.Result == nil {
+		return "when <function>"
+	}
+	return fmt.Sprintf("when `%s`", condition.Source())
+}
+
+// Source describes what the condition evaluates: a shell test, the result of a registered action or a function
+func (condition *Condition) Source() string {
+	switch {
+	case len(condition.Shell) > 0:
+		return condition.Shell
+	case condition.Result != nil:
+		return condition.Result.String()
+	}
+	return "<function>"
+}
+
+// (internal)
+// Evaluates the condition of the node, if any
+// Returns the results to use in place of running the node when it shouldn't run
+func (blueprint *SerialBlueprint) guard(ctx context.Context) (Results, bool) {
+	condition := blueprint.When
+
+	if condition == nil {
+		return Results{}, true
+	}
+
+	if condition.Eval == nil {
+		return blueprint.failedResults(fmt.Errorf("Condition of %s is not bound", blueprint.Name)), false
+	}
+
+	ok, err := condition.Eval(ctx)
+
+	if err != nil {
+		return blueprint.failedResults(fmt.Errorf("Failed to evaluate the condition of %s: %w", blueprint.Name, err)), false
+	}
+
+	if !ok {
+		return blueprint.skippedResults(fmt.Sprintf("Condition not met (%s)", condition)), false
+	}
+
+	return Results{}, true
+}
+
+// (internal)
+// Records every action of the blueprint as skipped, without running them
+func (blueprint *SerialBlueprint) skippedResults(reason string) Results {
+	results := Results{Success: true}
+
+	if blueprint.Function != nil {
+		results.Traces = append(results.Traces, Trace{
+			Name:    blueprint.Name,
+			Group:   blueprint.Group,
+			ID:      blueprint.ID,
+			Step:    blueprint.step,
+			Status:  StatusSkipped,
+			Details: reason,
+		})
+	}
+
+	for _, child := range blueprint.Children {
+		if node := serialNode(child); node != nil {
+			results.Merge(node.skippedResults(reason))
+		}
+	}
+
+	return results
+}
+
+// (internal)
+func (blueprint *SerialBlueprint) failedResults(err error) Results {
+	return Results{
+		Success:    false,
+		ErrorCount: 1,
+		Traces: []Trace{{
+			Name:   blueprint.Name,
+			Group:  blueprint.Group,
+			Status: StatusFailed,
+			Error:  err,
+		}},
+	}
+}
@@ -0,0 +1,89 @@
+package blueprint_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	. "github.com/patrixr/glue/pkg/blueprint"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConditions(t *testing.T) {
+	holds := func(ok bool) *Condition {
+		return &Condition{Eval: func(ctx context.Context) (bool, error) { return ok, nil }}
+	}
+
+	t.Run("actions are skipped when their condition doesn't hold", func(t *testing.T) {
+		ran := []string{}
+		plan := NewSerialBlueprint("root")
+
+		for name, condition := range map[string]*Condition{"yes": holds(true), "no": holds(false)} {
+			plan.Action(ActionDef{
+				Name: name,
+				When: condition,
+				Fn: func(ctx context.Context, trace *Trace) error {
+					ran = append(ran, name)
+					return nil
+				},
+			})
+		}
+
+		results := plan.Execute(context.Background())
+
+		assert.Equal(t, []string{"yes"}, ran)
+		assert.True(t, results.Success)
+		assert.Equal(t, 1, results.Count(StatusSkipped))
+	})
+
+	t.Run("every action of a group is skipped", func(t *testing.T) {
+		plan := NewSerialBlueprint("root")
+		group := NewSerialBlueprint("group")
+		group.When = holds(false)
+		nested := NewSerialBlueprint("nested")
+
+		for _, bp := range []Blueprint{group, nested} {
+			bp.Action(ActionDef{
+				Name: "action",
+				Fn: func(ctx context.Context, trace *Trace) error {
+					return errors.New("should not run")
+				},
+			})
+		}
+
+		group.Add(nested)
+		plan.Add(group)
+
+		results := plan.Execute(context.Background())
+
+		assert.True(t, results.Success)
+		assert.Len(t, results.Traces, 2)
+		assert.Equal(t, 2, results.Count(StatusSkipped))
+	})
+
+	t.Run("conditions failing to evaluate are errors", func(t *testing.T) {
+		plan := NewSerialBlueprint("root")
+		plan.Action(ActionDef{
+			Name: "action",
+			When: &Condition{Eval: func(ctx context.Context) (bool, error) { return false, errors.New("boom") }},
+			Fn:   func(ctx context.Context, trace *Trace) error { return nil },
+		})
+
+		results := plan.Execute(context.Background())
+
+		assert.False(t, results.Success)
+		assert.Equal(t, StatusFailed, results.Traces[0].Status)
+	})
+
+	t.Run("only shell conditions can be saved", func(t *testing.T) {
+		plan := NewSerialBlueprint("root")
+		plan.Action(ActionDef{Name: "action", Module: "Foo", When: &Condition{Shell: "test -f x"}})
+
+		var buf bytes.Buffer
+		assert.NoError(t, Save(plan, "glue.lua", &buf))
+
+		plan.Action(ActionDef{Name: "action", Module: "Foo", When: holds(true)})
+		assert.Error(t, Save(plan, "glue.lua", &bytes.Buffer{}))
+	})
+}
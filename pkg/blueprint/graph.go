@@ -0,0 +1,170 @@
+package blueprint
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Resolve orders the blueprint according to the dependencies declared by its groups
+//
+// Dependencies between nested groups are lifted to the level where both groups share a parent,
+// the children of each blueprint are then topologically sorted, preserving the declaration order where possible.
+// An error is returned if a dependency cycle is found, or if a dependency is unknown and allowMissing is false
+// Once sorted, the actions are numbered in execution order, see SkipSteps
+func Resolve(root Blueprint, allowMissing bool) error {
+	chains := map[string][]Blueprint{}
+	groups := []string{}
+
+	var collect func(bp Blueprint, chain []Blueprint)
+
+	collect = func(bp Blueprint, chain []Blueprint) {
+		node := serialNode(bp)
+
+		if node == nil {
+			return
+		}
+
+		chain = append(chain[:len(chain):len(chain)], bp)
+
+		if len(node.Module) == 0 && len(node.Group) > 0 {
+			key := strings.ToLower(node.Group)
+			chains[key] = chain
+			groups = append(groups, key)
+		}
+
+		for _, child := range node.Children {
+			collect(child, chain)
+		}
+	}
+
+	collect(root, []Blueprint{})
+
+	edges := map[Blueprint]map[Blueprint][]Blueprint{}
+
+	for _, key := range groups {
+		chain := chains[key]
+		group := serialNode(chain[len(chain)-1])
+
+		for _, dep := range group.DependsOn {
+			depChain, ok := chains[strings.ToLower(dep)]
+
+			if !ok {
+				if allowMissing {
+					continue
+				}
+				return fmt.Errorf("Group %s depends on unknown group %s", group.Group, dep)
+			}
+
+			k := 0
+			for k < len(chain) && k < len(depChain) && chain[k] == depChain[k] {
+				k++
+			}
+
+			if k == len(chain) || k == len(depChain) {
+				return fmt.Errorf("Group %s cannot depend on %s, one is nested in the other", group.Group, dep)
+			}
+
+			parent := chain[k-1]
+
+			if edges[parent] == nil {
+				edges[parent] = map[Blueprint][]Blueprint{}
+			}
+
+			edges[parent][chain[k]] = append(edges[parent][chain[k]], depChain[k])
+		}
+	}
+
+	for parent, deps := range edges {
+		if err := sortChildren(serialNode(parent), deps); err != nil {
+			return err
+		}
+	}
+
+	numberSteps(root)
+
+	return nil
+}
+
+// (internal)
+// Topologically sorts the children of the node, the resulting dependencies are stored as indices
+func sortChildren(node *SerialBlueprint, deps map[Blueprint][]Blueprint) error {
+	index := map[Blueprint]int{}
+
+	for i, child := range node.Children {
+		index[child] = i
+	}
+
+	indegree := make([]int, len(node.Children))
+	dependents := make([][]int, len(node.Children))
+
+	for child, requirements := range deps {
+		for _, req := range requirements {
+			indegree[index[child]]++
+			dependents[index[req]] = append(dependents[index[req]], index[child])
+		}
+	}
+
+	ready := []int{}
+	for i, degree := range indegree {
+		if degree == 0 {
+			ready = append(ready, i)
+		}
+	}
+
+	order := []int{}
+
+	for len(ready) > 0 {
+		sort.Ints(ready)
+		current := ready[0]
+		ready = ready[1:]
+		order = append(order, current)
+
+		for _, dependent := range dependents[current] {
+			indegree[dependent]--
+			if indegree[dependent] == 0 {
+				ready = append(ready, dependent)
+			}
+		}
+	}
+
+	if len(order) < len(node.Children) {
+		cycle := []string{}
+		for i, degree := range indegree {
+			if degree > 0 {
+				cycle = append(cycle, serialNode(node.Children[i]).Name)
+			}
+		}
+		return fmt.Errorf("Dependency cycle detected between groups %s", strings.Join(cycle, ", "))
+	}
+
+	sorted := make([]Blueprint, len(order))
+	position := map[Blueprint]int{}
+
+	for i, idx := range order {
+		sorted[i] = node.Children[idx]
+		position[sorted[i]] = i
+	}
+
+	node.Children = sorted
+	node.waits = make([][]int, len(sorted))
+
+	for child, requirements := range deps {
+		for _, req := range requirements {
+			node.waits[position[child]] = append(node.waits[position[child]], position[req])
+		}
+	}
+
+	return nil
+}
+
+// (internal)
+func serialNode(bp Blueprint) *SerialBlueprint {
+	switch node := bp.(type) {
+	case *SerialBlueprint:
+		return node
+	case *ParallelBlueprint:
+		return &node.SerialBlueprint
+	}
+	return nil
+}
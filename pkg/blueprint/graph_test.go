@@ -0,0 +1,73 @@
+package blueprint_test
+
+import (
+	"testing"
+
+	. "github.com/patrixr/glue/pkg/blueprint"
+	"github.com/stretchr/testify/assert"
+)
+
+func group(path string, name string, deps ...string) *SerialBlueprint {
+	bp := NewSerialBlueprint(name)
+	bp.Group = path
+	bp.DependsOn = deps
+	return bp
+}
+
+func names(bp *SerialBlueprint) []string {
+	res := []string{}
+	for _, child := range bp.Children {
+		res = append(res, child.(*SerialBlueprint).Name)
+	}
+	return res
+}
+
+func TestResolve(t *testing.T) {
+	t.Run("siblings are sorted by dependencies", func(t *testing.T) {
+		root := NewSerialBlueprint("<root>")
+		root.Add(group("a", "a", "c"))
+		root.Add(group("b", "b"))
+		root.Add(group("c", "c", "b"))
+
+		assert.NoError(t, Resolve(root, false))
+		assert.Equal(t, []string{"b", "c", "a"}, names(root))
+	})
+
+	t.Run("nested dependencies are lifted to a common parent", func(t *testing.T) {
+		root := NewSerialBlueprint("<root>")
+		configs := group("configs", "configs")
+		configs.Add(group("configs.nvim", "nvim", "homebrew", "configs.git"))
+		configs.Add(group("configs.git", "git"))
+		root.Add(configs)
+		root.Add(group("homebrew", "homebrew"))
+
+		assert.NoError(t, Resolve(root, false))
+		assert.Equal(t, []string{"homebrew", "configs"}, names(root))
+		assert.Equal(t, []string{"git", "nvim"}, names(configs))
+	})
+
+	t.Run("cycles are detected", func(t *testing.T) {
+		root := NewSerialBlueprint("<root>")
+		root.Add(group("a", "a", "b"))
+		root.Add(group("b", "b", "a"))
+
+		assert.ErrorContains(t, Resolve(root, false), "cycle")
+	})
+
+	t.Run("unknown dependencies are rejected unless allowed", func(t *testing.T) {
+		root := NewSerialBlueprint("<root>")
+		root.Add(group("a", "a", "missing"))
+
+		assert.Error(t, Resolve(root, false))
+		assert.NoError(t, Resolve(root, true))
+	})
+
+	t.Run("a group cannot depend on its parent", func(t *testing.T) {
+		root := NewSerialBlueprint("<root>")
+		parent := group("parent", "parent")
+		parent.Add(group("parent.child", "child", "parent"))
+		root.Add(parent)
+
+		assert.Error(t, Resolve(root, false))
+	})
+}
@@ -0,0 +1,62 @@
+package blueprint
+
+import (
+	"context"
+	"strings"
+)
+
+// AddHandler registers a blueprint which only runs at the end of the execution,
+// if an action notifying it reported a change. Handlers are identified by their name
+func (blueprint *SerialBlueprint) AddHandler(handler Blueprint) {
+	if node := serialNode(handler); node != nil {
+		node.Handler = true
+	}
+
+	blueprint.Handlers = append(blueprint.Handlers, handler)
+}
+
+// (internal)
+// Runs the handlers notified by the changed actions of the results, in declaration order
+// Handlers can notify the handlers declared after them, none of them run once the run is stopped
+func (blueprint *SerialBlueprint) runHandlers(ctx context.Context, results Results, mode runMode) Results {
+	handled := Results{Success: true}
+
+	for _, handler := range blueprint.Handlers {
+		node := serialNode(handler)
+
+		if node == nil || (!notified(results, node.Name) && !notified(handled, node.Name)) {
+			continue
+		}
+
+		if ctx.Err() != nil {
+			handled.Merge(cancelledResults(handler, interruptedReason))
+			continue
+		}
+
+		if results.Stopped || handled.Stopped {
+			handled.Merge(cancelledResults(handler, blueprint.interruptReason(true)))
+			continue
+		}
+
+		blueprint.inherit(handler)
+		handled.Merge(runBlueprint(ctx, handler, mode))
+	}
+
+	return handled
+}
+
+// (internal)
+func notified(results Results, handler string) bool {
+	for _, trace := range results.Traces {
+		if !trace.Changed {
+			continue
+		}
+
+		for _, name := range trace.Notify {
+			if strings.EqualFold(name, handler) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,71 @@
+package blueprint_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	. "github.com/patrixr/glue/pkg/blueprint"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandlers(t *testing.T) {
+	build := func(changed bool, ran *[]string) *SerialBlueprint {
+		plan := NewSerialBlueprint("root")
+
+		plan.Action(ActionDef{
+			Name:   "config",
+			Notify: []string{"reload"},
+			Fn: func(ctx context.Context, trace *Trace) error {
+				trace.Changed = changed
+				return nil
+			},
+		})
+
+		for _, name := range []string{"reload", "restart"} {
+			handler := NewSerialBlueprint(name)
+			handler.Action(ActionDef{
+				Name: name,
+				Fn: func(ctx context.Context, trace *Trace) error {
+					*ran = append(*ran, name)
+					return nil
+				},
+			})
+			plan.AddHandler(handler)
+		}
+
+		return plan
+	}
+
+	t.Run("notified handlers run after every action", func(t *testing.T) {
+		ran := []string{}
+		results := build(true, &ran).Execute(context.Background())
+
+		assert.Equal(t, []string{"reload"}, ran)
+		assert.Len(t, results.Traces, 2)
+		assert.Equal(t, "reload", results.Traces[1].Name)
+	})
+
+	t.Run("handlers don't run when nothing changed", func(t *testing.T) {
+		ran := []string{}
+		build(false, &ran).Execute(context.Background())
+
+		assert.Empty(t, ran)
+	})
+
+	t.Run("handlers are kept when saving the blueprint", func(t *testing.T) {
+		ran := []string{}
+		plan := build(true, &ran)
+		plan.Children[0].(*SerialBlueprint).Module = "Config"
+
+		var buf bytes.Buffer
+		assert.NoError(t, Save(plan, "glue.lua", &buf))
+
+		loaded, err := Load(&buf, func(action ActionDef) (ActionDef, error) {
+			return action, nil
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, "+ root\n  + config [config-1-74234e]\n  + reload (handler)\n    + reload\n  + restart (handler)\n    + restart\n", loaded.PrettyPrint())
+	})
+}
@@ -0,0 +1,96 @@
+package blueprint
+
+import "fmt"
+
+// Defines what happens to the remaining steps of a group when one of its actions fails
+// Failures are always reported to the enclosing groups, which apply their own policy
+type ErrorPolicy string
+
+const (
+	// Uses the policy of the enclosing group, the root group continues by default
+	OnErrorInherit ErrorPolicy = ""
+	// The remaining steps run regardless of the failure
+	OnErrorContinue ErrorPolicy = "continue"
+	// The remaining steps of the group are not run
+	OnErrorStopGroup ErrorPolicy = "stop-group"
+	// Nothing else runs, in the group or anywhere else in the plan
+	OnErrorStop ErrorPolicy = "stop"
+)
+
+func ValidErrorPolicy(policy string) bool {
+	switch ErrorPolicy(policy) {
+	case OnErrorInherit, OnErrorContinue, OnErrorStopGroup, OnErrorStop:
+		return true
+	}
+	return false
+}
+
+// (internal)
+// Returns the policy applied to the children of the blueprint
+func (blueprint *SerialBlueprint) errorPolicy() ErrorPolicy {
+	if blueprint.OnError != OnErrorInherit {
+		return blueprint.OnError
+	}
+
+	if blueprint.inheritedPolicy != OnErrorInherit {
+		return blueprint.inheritedPolicy
+	}
+
+	return OnErrorContinue
+}
+
+// (internal)
+// Passes the policy of the blueprint down to a child, before it runs
+func (blueprint *SerialBlueprint) inherit(child Blueprint) {
+	if node := serialNode(child); node != nil {
+		node.inheritedPolicy = blueprint.errorPolicy()
+	}
+}
+
+// (internal)
+// Decides, from the results of a child, whether the remaining children of the blueprint should not run
+// The second value reports whether the whole run has to stop
+func (blueprint *SerialBlueprint) interrupted(child Results) (bool, bool) {
+	if child.Stopped {
+		return true, true
+	}
+
+	if child.Success {
+		return false, false
+	}
+
+	switch blueprint.errorPolicy() {
+	case OnErrorStop:
+		return true, true
+	case OnErrorStopGroup:
+		return true, false
+	}
+
+	return false, false
+}
+
+// (internal)
+func (blueprint *SerialBlueprint) interruptReason(stopped bool) string {
+	if stopped {
+		return "Not run, the run was stopped after an error"
+	}
+	return fmt.Sprintf("Not run, %s was stopped after an error", blueprint.Name)
+}
+
+// (internal)
+// Records every action of the blueprint as cancelled, without running them
+func cancelledResults(blueprint Blueprint, reason string) Results {
+	node := serialNode(blueprint)
+
+	if node == nil {
+		return Results{Success: true}
+	}
+
+	results := node.skippedResults(reason)
+
+	for i := range results.Traces {
+		results.Traces[i].Cancelled = true
+	}
+
+	return results
+}
@@ -0,0 +1,109 @@
+package blueprint_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	. "github.com/patrixr/glue/pkg/blueprint"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestErrorPolicies(t *testing.T) {
+	var ran []string
+
+	action := func(name string, fail bool) ActionDef {
+		return ActionDef{
+			Name:  name,
+			Group: "group",
+			Fn: func(ctx context.Context, trace *Trace) error {
+				ran = append(ran, name)
+				if fail {
+					return errors.New("failed")
+				}
+				return nil
+			},
+		}
+	}
+
+	build := func(root *SerialBlueprint, policy ErrorPolicy) *SerialBlueprint {
+		group := NewSerialBlueprint("group")
+		group.OnError = policy
+		group.Action(action("a", false))
+		group.Action(action("b", true))
+		group.Action(action("c", false))
+		root.Add(group)
+		root.Action(action("d", false))
+		return root
+	}
+
+	t.Run("failures don't stop the run by default", func(t *testing.T) {
+		ran = nil
+		results := build(NewSerialBlueprint("root"), OnErrorInherit).Execute(context.Background())
+
+		assert.Equal(t, []string{"a", "b", "c", "d"}, ran)
+		assert.False(t, results.Success)
+		assert.False(t, results.Stopped)
+	})
+
+	t.Run("stop-group only cancels the rest of the group", func(t *testing.T) {
+		ran = nil
+		results := build(NewSerialBlueprint("root"), OnErrorStopGroup).Execute(context.Background())
+
+		assert.Equal(t, []string{"a", "b", "d"}, ran)
+		assert.True(t, results.Traces[2].Cancelled)
+		assert.Equal(t, StatusSkipped, results.Traces[2].Status)
+		assert.False(t, results.Stopped)
+	})
+
+	t.Run("stop cancels everything else", func(t *testing.T) {
+		ran = nil
+		results := build(NewSerialBlueprint("root"), OnErrorStop).Execute(context.Background())
+
+		assert.Equal(t, []string{"a", "b"}, ran)
+		assert.Len(t, results.Traces, 4)
+		assert.True(t, results.Stopped)
+		assert.Equal(t, 1, results.ErrorCount, "cancelled actions are not errors")
+	})
+
+	t.Run("groups inherit the policy of their parent", func(t *testing.T) {
+		ran = nil
+		root := NewSerialBlueprint("root")
+		root.OnError = OnErrorStop
+		build(root, OnErrorInherit).Execute(context.Background())
+
+		assert.Equal(t, []string{"a", "b"}, ran)
+	})
+
+	t.Run("parallel groups don't start new children once stopped", func(t *testing.T) {
+		ran = nil
+		root := NewParallelBlueprint("root", 1)
+		root.OnError = OnErrorStop
+		root.Action(action("a", true))
+		root.Action(action("b", true))
+
+		results := root.Execute(context.Background())
+
+		assert.Len(t, ran, 1, "only one child runs at a time, the first to fail stops the other")
+		assert.True(t, results.Stopped)
+		assert.Equal(t, 1, results.ErrorCount)
+		assert.Equal(t, 1, results.Count(StatusSkipped))
+	})
+
+	t.Run("ignored errors don't fail the run", func(t *testing.T) {
+		ran = nil
+		root := NewSerialBlueprint("root")
+		root.OnError = OnErrorStop
+		ignored := action("a", true)
+		ignored.IgnoreErrors = true
+		root.Action(ignored)
+		root.Action(action("b", false))
+
+		results := root.Execute(context.Background())
+
+		assert.Equal(t, []string{"a", "b"}, ran)
+		assert.True(t, results.Success)
+		assert.True(t, results.Traces[0].Ignored)
+		assert.Equal(t, StatusFailed, results.Traces[0].Status)
+	})
+}
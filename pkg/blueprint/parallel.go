@@ -0,0 +1,186 @@
+package blueprint
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Number of concurrent jobs used by parallel blueprints when no limit is given
+const DefaultJobs = 4
+
+// A blueprint which executes its children concurrently
+// The number of children running at the same time is limited by Jobs
+type ParallelBlueprint struct {
+	SerialBlueprint
+	Jobs int `json:"jobs"`
+}
+
+func NewParallelBlueprint(name string, jobs int) *ParallelBlueprint {
+	if jobs <= 0 {
+		jobs = DefaultJobs
+	}
+
+	return &ParallelBlueprint{
+		SerialBlueprint: *NewSerialBlueprint(name),
+		Jobs:            jobs,
+	}
+}
+
+func (blueprint *ParallelBlueprint) Execute(ctx context.Context) Results {
+	return blueprint.run(ctx, modeExecute)
+}
+
+func (blueprint *ParallelBlueprint) Check(ctx context.Context) Results {
+	return blueprint.run(ctx, modeCheck)
+}
+
+// (internal)
+func (blueprint *ParallelBlueprint) run(ctx context.Context, mode runMode) Results {
+	if results, ok := blueprint.guard(ctx); !ok {
+		return results
+	}
+
+	start := time.Now()
+	results := blueprint.runFunction(ctx, mode)
+
+	// Each child writes to its own slot, they are merged in order once all are done
+	slots := make([]Results, len(blueprint.Children))
+	done := make([]chan struct{}, len(blueprint.Children))
+	semaphore := make(chan struct{}, max(blueprint.Jobs, 1))
+	wg := sync.WaitGroup{}
+
+	// set once a failing child interrupts the group, children which haven't started yet are cancelled
+	var interrupt struct {
+		sync.Mutex
+		interrupted bool
+		stopped     bool
+	}
+
+	for i := range done {
+		done[i] = make(chan struct{})
+	}
+
+	for i, child := range blueprint.Children {
+		blueprint.inherit(child)
+		wg.Add(1)
+
+		go func() {
+			defer func() {
+				close(done[i])
+				wg.Done()
+			}()
+
+			// children are sorted by Resolve, waiting on dependencies cannot deadlock
+			if i < len(blueprint.waits) {
+				for _, dep := range blueprint.waits[i] {
+					<-done[dep]
+				}
+			}
+
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			interrupt.Lock()
+			interrupted, stopped := interrupt.interrupted, interrupt.stopped
+			interrupt.Unlock()
+
+			if interrupted {
+				slots[i] = cancelledResults(child, blueprint.interruptReason(stopped))
+				return
+			}
+
+			if ctx.Err() != nil {
+				slots[i] = cancelledResults(child, interruptedReason)
+				return
+			}
+
+			slots[i] = runBlueprint(ctx, child, mode)
+
+			if interrupted, stopped := blueprint.interrupted(slots[i]); interrupted {
+				interrupt.Lock()
+				interrupt.interrupted = true
+				interrupt.stopped = interrupt.stopped || stopped
+				interrupt.Unlock()
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	results.Stopped = interrupt.stopped || ctx.Err() != nil
+
+	for _, res := range slots {
+		results.Merge(res)
+	}
+
+	results.Merge(blueprint.runHandlers(ctx, results, mode))
+
+	results.TimeElapsedSec = int(time.Since(start).Seconds())
+
+	return results
+}
+
+func (blueprint *ParallelBlueprint) UnmarshalJSON(data []byte) error {
+	var jobs struct {
+		Jobs int `json:"jobs"`
+	}
+
+	if err := json.Unmarshal(data, &jobs); err != nil {
+		return err
+	}
+
+	if err := blueprint.SerialBlueprint.UnmarshalJSON(data); err != nil {
+		return err
+	}
+
+	blueprint.Jobs = jobs.Jobs
+
+	return nil
+}
+
+func (blueprint *ParallelBlueprint) PrettyPrint() string {
+	builder := strings.Builder{}
+	blueprint.prettyPrintRecursive(&builder, 0)
+	return builder.String()
+}
+
+// (internal)
+func (blueprint *ParallelBlueprint) prettyPrintRecursive(builder *strings.Builder, depth int) {
+	for i := 0; i < depth; i++ {
+		builder.WriteString("  ")
+	}
+	builder.WriteString("+ ")
+	builder.WriteString(blueprint.describe())
+	builder.WriteString(fmt.Sprintf(" (parallel, %d jobs)", blueprint.Jobs))
+	builder.WriteString("\n")
+
+	for _, child := range blueprint.Nested() {
+		prettyPrintChild(builder, child, depth+1)
+	}
+}
+
+// (internal)
+// Decodes a serialized blueprint, parallel blueprints are identified by their jobs field
+func decodeBlueprint(data []byte) (Blueprint, error) {
+	var kind struct {
+		Jobs int `json:"jobs"`
+	}
+
+	if err := json.Unmarshal(data, &kind); err != nil {
+		return nil, err
+	}
+
+	if kind.Jobs > 0 {
+		blueprint := &ParallelBlueprint{}
+		err := json.Unmarshal(data, blueprint)
+		return blueprint, err
+	}
+
+	blueprint := &SerialBlueprint{}
+	err := json.Unmarshal(data, blueprint)
+	return blueprint, err
+}
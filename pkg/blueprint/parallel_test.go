@@ -0,0 +1,72 @@
+package blueprint_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/patrixr/glue/pkg/blueprint"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParallelBlueprint(t *testing.T) {
+	t.Run("children never exceed the concurrency limit", func(t *testing.T) {
+		var running, peak int32
+
+		plan := NewParallelBlueprint("parallel", 2)
+
+		for i := 0; i < 6; i++ {
+			plan.Action(ActionDef{
+				Name: fmt.Sprintf("action-%d", i),
+				Fn: func(ctx context.Context, trace *Trace) error {
+					current := atomic.AddInt32(&running, 1)
+					defer atomic.AddInt32(&running, -1)
+
+					for {
+						old := atomic.LoadInt32(&peak)
+						if current <= old || atomic.CompareAndSwapInt32(&peak, old, current) {
+							break
+						}
+					}
+
+					time.Sleep(10 * time.Millisecond)
+					return nil
+				},
+			})
+		}
+
+		results := plan.Execute(context.Background())
+
+		assert.Len(t, results.Traces, 6)
+		assert.LessOrEqual(t, peak, int32(2))
+		assert.Greater(t, peak, int32(1))
+	})
+
+	t.Run("traces are merged in declaration order", func(t *testing.T) {
+		plan := NewParallelBlueprint("parallel", 3)
+
+		for i := 0; i < 3; i++ {
+			delay := time.Duration(3-i) * 5 * time.Millisecond
+			plan.Action(ActionDef{
+				Name: fmt.Sprintf("action-%d", i),
+				Fn: func(ctx context.Context, trace *Trace) error {
+					time.Sleep(delay)
+					if i == 1 {
+						return errors.New("failed")
+					}
+					return nil
+				},
+			})
+		}
+
+		results := plan.Execute(context.Background())
+
+		assert.Equal(t, 1, results.ErrorCount)
+		assert.Equal(t, "action-0", results.Traces[0].Name)
+		assert.Equal(t, "action-1", results.Traces[1].Name)
+		assert.Equal(t, "action-2", results.Traces[2].Name)
+	})
+}
@@ -0,0 +1,213 @@
+package blueprint
+
+import (
+	"fmt"
+	"strings"
+)
+
+// The phases of a plan run one after the other, whatever the order their groups and actions were declared in
+type Phase string
+
+const (
+	// Uses the phase of the enclosing group, the main phase by default
+	PhaseInherit Phase = ""
+	// Prepares the machine, e.g installing package managers
+	PhasePre  Phase = "pre"
+	PhaseMain Phase = "main"
+	// Runs once everything else is done, e.g upgrades and cleanups
+	PhasePost Phase = "post"
+)
+
+// The phases in execution order
+var Phases = []Phase{PhasePre, PhaseMain, PhasePost}
+
+// ParsePhase validates the name of a phase, `bootstrap` is an alias of the pre phase
+func ParsePhase(name string) (Phase, error) {
+	name = strings.ToLower(name)
+
+	if name == "bootstrap" {
+		return PhasePre, nil
+	}
+
+	switch phase := Phase(name); phase {
+	case PhaseInherit, PhasePre, PhaseMain, PhasePost:
+		return phase, nil
+	}
+
+	return PhaseInherit, fmt.Errorf("Invalid phase '%s'. Expected pre (or bootstrap), main or post", name)
+}
+
+// (internal)
+func (phase Phase) order() int {
+	for i, p := range Phases {
+		if p == phase {
+			return i
+		}
+	}
+	return 1
+}
+
+// SplitPhases moves the nodes of the plan into one group per phase, run in order
+//
+// A node runs in its own phase, or in the one of its enclosing group. The groups enclosing a node moved to another phase
+// are recreated in that phase, with their conditions and policies but without their dependencies.
+// Handlers keep running at the end of the plan. The plan is returned unchanged if it only uses the main phase,
+// otherwise the returned root runs the phases one after the other, concurrently within each phase if the root was parallel
+func SplitPhases(root Blueprint) (Blueprint, error) {
+	node := serialNode(root)
+
+	if node == nil || !usesPhases(root) {
+		return root, nil
+	}
+
+	if err := checkPhaseDependencies(root); err != nil {
+		return nil, err
+	}
+
+	parts := map[Phase][]Blueprint{}
+
+	for _, child := range node.Children {
+		for phase, part := range partition(child, PhaseMain) {
+			parts[phase] = append(parts[phase], part)
+		}
+	}
+
+	split := NewSerialBlueprint(node.Name)
+	split.OnError = node.OnError
+	split.Handlers = node.Handlers
+
+	for _, phase := range Phases {
+		if len(parts[phase]) == 0 {
+			continue
+		}
+
+		var container Blueprint
+
+		if parallel, ok := root.(*ParallelBlueprint); ok {
+			group := NewParallelBlueprint(string(phase), parallel.Jobs)
+			group.Phase = phase
+			group.Children = parts[phase]
+			container = group
+		} else {
+			group := NewSerialBlueprint(string(phase))
+			group.Phase = phase
+			group.Children = parts[phase]
+			container = group
+		}
+
+		split.Children = append(split.Children, container)
+	}
+
+	return split, nil
+}
+
+// (internal)
+// The groups created by SplitPhases have no key of their own
+func (blueprint *SerialBlueprint) isPhase() bool {
+	return blueprint.Phase != PhaseInherit && len(blueprint.Group) == 0 && len(blueprint.Module) == 0
+}
+
+// (internal)
+func usesPhases(root Blueprint) bool {
+	_, found := Find(root, func(node Node) bool {
+		return !node.Handler && node.Phase != PhaseInherit && node.Phase != PhaseMain
+	})
+	return found
+}
+
+// Within returns the phase of a node, given the phase of its enclosing group
+func (phase Phase) Within(inherited Phase) Phase {
+	if phase == PhaseInherit {
+		return inherited
+	}
+	return phase
+}
+
+// (internal)
+// Splits a node by phase, the node itself is kept in its own phase and copied to the others
+func partition(bp Blueprint, inherited Phase) map[Phase]Blueprint {
+	node := serialNode(bp)
+	phase := node.Phase.Within(inherited)
+
+	if node.isAction() {
+		return map[Phase]Blueprint{phase: bp}
+	}
+
+	children := map[Phase][]Blueprint{}
+
+	for _, child := range node.Children {
+		for p, part := range partition(child, phase) {
+			children[p] = append(children[p], part)
+		}
+	}
+
+	parts := map[Phase]Blueprint{}
+
+	for p, nested := range children {
+		if p == phase {
+			continue
+		}
+		parts[p] = phaseCopy(bp, nested)
+	}
+
+	bp.SetNested(append(children[phase], node.Handlers...))
+	parts[phase] = bp
+
+	return parts
+}
+
+// (internal)
+// A copy of the group holding its children of another phase
+// The copy has no key, the dependencies of the group only apply to its own phase
+func phaseCopy(bp Blueprint, children []Blueprint) Blueprint {
+	reset := func(node *SerialBlueprint) {
+		node.ID = ""
+		node.Group = ""
+		node.DependsOn = nil
+		node.Phase = PhaseInherit
+		node.Children = children
+		node.Handlers = nil
+		node.waits = nil
+	}
+
+	if parallel, ok := bp.(*ParallelBlueprint); ok {
+		clone := *parallel
+		reset(&clone.SerialBlueprint)
+		return &clone
+	}
+
+	clone := *serialNode(bp)
+	reset(&clone)
+	return &clone
+}
+
+// (internal)
+// A group cannot wait for a group of a later phase
+func checkPhaseDependencies(root Blueprint) error {
+	phases := map[string]Phase{}
+	inherited := []Phase{PhaseMain}
+	groups := []Node{}
+
+	Walk(root, func(node Node) error {
+		inherited = append(inherited[:node.Depth+1], node.Phase.Within(inherited[node.Depth]))
+
+		if !node.Action && len(node.Group) > 0 {
+			phases[strings.ToLower(node.Group)] = inherited[node.Depth+1]
+			groups = append(groups, node)
+		}
+
+		return nil
+	})
+
+	for _, group := range groups {
+		phase := phases[strings.ToLower(group.Group)]
+
+		for _, dep := range group.DependsOn {
+			if depPhase, ok := phases[strings.ToLower(dep)]; ok && depPhase.order() > phase.order() {
+				return fmt.Errorf("Group %s runs in the %s phase and cannot depend on %s, which runs in the later %s phase", group.Group, phase, dep, depPhase)
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,81 @@
+package blueprint_test
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/patrixr/glue/pkg/blueprint"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPhases(t *testing.T) {
+	build := func(ran *[]string) *SerialBlueprint {
+		record := func(name string) ActionFunc {
+			return func(ctx context.Context, trace *Trace) error {
+				*ran = append(*ran, name)
+				return nil
+			}
+		}
+
+		plan := NewSerialBlueprint("root")
+
+		upgrades := NewSerialBlueprint("upgrades")
+		upgrades.Group = "upgrades"
+		upgrades.Phase = PhasePost
+		upgrades.Action(ActionDef{Name: "upgrade", Group: "upgrades", Fn: record("upgrade")})
+		plan.Add(upgrades)
+
+		configs := NewSerialBlueprint("configs")
+		configs.Group = "configs"
+		configs.When = &Condition{Shell: "true", Eval: func(ctx context.Context) (bool, error) { return true, nil }}
+		configs.Action(ActionDef{Name: "copy", Group: "configs", Fn: record("copy")})
+		configs.Action(ActionDef{Name: "install", Group: "configs", Phase: PhasePre, Fn: record("install")})
+		plan.Add(configs)
+
+		return plan
+	}
+
+	t.Run("runs the phases in order", func(t *testing.T) {
+		ran := []string{}
+		plan, err := SplitPhases(build(&ran))
+
+		assert.NoError(t, err)
+		assert.NoError(t, Resolve(plan, false))
+		assert.Equal(t, "+ root\n  + pre (phase)\n    + configs (when `true`)\n      + install [configs/install-1-74234e]\n  + main (phase)\n    + configs (when `true`)\n      + copy [configs/copy-1-74234e]\n  + post (phase)\n    + upgrades\n      + upgrade [upgrades/upgrade-1-74234e]\n", plan.PrettyPrint())
+
+		plan.Execute(context.Background())
+		assert.Equal(t, []string{"install", "copy", "upgrade"}, ran)
+	})
+
+	t.Run("leaves plans without phases unchanged", func(t *testing.T) {
+		plan := NewSerialBlueprint("root")
+		plan.Action(ActionDef{Name: "copy", Fn: noop})
+
+		split, err := SplitPhases(plan)
+
+		assert.NoError(t, err)
+		assert.Same(t, plan, split)
+	})
+
+	t.Run("rejects dependencies on a later phase", func(t *testing.T) {
+		plan := build(&[]string{})
+		bootstrap := NewSerialBlueprint("bootstrap")
+		bootstrap.Group = "bootstrap"
+		bootstrap.Phase = PhasePre
+		bootstrap.DependsOn = []string{"configs"}
+		plan.Add(bootstrap)
+
+		_, err := SplitPhases(plan)
+
+		assert.Error(t, err)
+	})
+
+	t.Run("accepts bootstrap as an alias of pre", func(t *testing.T) {
+		phase, err := ParsePhase("bootstrap")
+		assert.NoError(t, err)
+		assert.Equal(t, PhasePre, phase)
+
+		_, err = ParsePhase("later")
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,56 @@
+package blueprint
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// The kinds of resources managed by actions
+const (
+	// A file or a directory, identified by its absolute path
+	ResourcePath = "path"
+	// A package installed by a package manager, e.g `brew:neovim`
+	ResourcePackage = "package"
+)
+
+// A resource of the machine managed by an action, such as a file or a package
+type Resource struct {
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+	// The part of the resource managed by the action, e.g a block of a file (optional)
+	// Actions managing different parts of a resource don't overlap
+	Part string `json:"part,omitempty"`
+}
+
+// Overlaps reports whether two actions managing the resources would interfere with each other
+// Paths overlap when they are the same, or when one of them contains the other
+func (resource Resource) Overlaps(other Resource) bool {
+	if resource.Kind != other.Kind {
+		return false
+	}
+
+	if len(resource.Part) > 0 && len(other.Part) > 0 && resource.Part != other.Part {
+		return false
+	}
+
+	if resource.Kind == ResourcePath {
+		return within(resource.Name, other.Name) || within(other.Name, resource.Name)
+	}
+
+	return resource.Name == other.Name
+}
+
+func (resource Resource) String() string {
+	if len(resource.Part) > 0 {
+		return fmt.Sprintf("%s %s (%s)", resource.Kind, resource.Name, resource.Part)
+	}
+	return fmt.Sprintf("%s %s", resource.Kind, resource.Name)
+}
+
+// (internal)
+func within(dir string, path string) bool {
+	dir = filepath.Clean(dir)
+	path = filepath.Clean(path)
+	return path == dir || strings.HasPrefix(path, strings.TrimSuffix(dir, string(filepath.Separator))+string(filepath.Separator))
+}
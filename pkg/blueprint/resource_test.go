@@ -0,0 +1,26 @@
+package blueprint_test
+
+import (
+	"testing"
+
+	. "github.com/patrixr/glue/pkg/blueprint"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResourceOverlaps(t *testing.T) {
+	file := Resource{Kind: ResourcePath, Name: "/home/.zshrc"}
+	block := Resource{Kind: ResourcePath, Name: "/home/.zshrc", Part: "# BEGIN A"}
+
+	assert.True(t, file.Overlaps(file))
+	assert.True(t, file.Overlaps(Resource{Kind: ResourcePath, Name: "/home/"}))
+	assert.False(t, file.Overlaps(Resource{Kind: ResourcePath, Name: "/home/.zshrc.d"}))
+	assert.False(t, file.Overlaps(Resource{Kind: ResourcePackage, Name: "/home/.zshrc"}))
+
+	// blocks with different markers share the file, but not with the file itself
+	assert.True(t, block.Overlaps(file))
+	assert.True(t, block.Overlaps(block))
+	assert.False(t, block.Overlaps(Resource{Kind: ResourcePath, Name: "/home/.zshrc", Part: "# BEGIN B"}))
+
+	assert.True(t, Resource{Kind: ResourcePackage, Name: "brew:neovim"}.Overlaps(Resource{Kind: ResourcePackage, Name: "brew:neovim"}))
+	assert.False(t, Resource{Kind: ResourcePackage, Name: "brew:neovim"}.Overlaps(Resource{Kind: ResourcePackage, Name: "cask:neovim"}))
+}
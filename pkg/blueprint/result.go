@@ -0,0 +1,99 @@
+package blueprint
+
+import "fmt"
+
+// The fields of the result of a registered action
+const (
+	ResultStdout  = "stdout"
+	ResultRC      = "rc"
+	ResultChanged = "changed"
+)
+
+var ResultFields = []string{ResultStdout, ResultRC, ResultChanged}
+
+// The outcome of a registered action, available to the actions running after it
+type Result struct {
+	Stdout  string
+	RC      int
+	Changed bool
+}
+
+// Field returns the value of a field of the result
+func (result Result) Field(name string) (any, error) {
+	switch name {
+	case ResultStdout:
+		return result.Stdout, nil
+	case ResultRC:
+		return result.RC, nil
+	case ResultChanged:
+		return result.Changed, nil
+	}
+
+	return nil, fmt.Errorf("Unknown result field '%s'. Expected stdout, rc or changed", name)
+}
+
+// Holds reports whether a condition on a field of the result holds:
+// the action changed something, exited with a zero rc, or printed something
+func (result Result) Holds(name string) (bool, error) {
+	switch name {
+	case ResultStdout:
+		return len(result.Stdout) > 0, nil
+	case ResultRC:
+		return result.RC == 0, nil
+	case ResultChanged:
+		return result.Changed, nil
+	}
+
+	return false, fmt.Errorf("Unknown result field '%s'. Expected stdout, rc or changed", name)
+}
+
+// Refers to a field of the result of a registered action
+// References are stored as data within the arguments of the actions using them, and resolved right before they run
+type ResultRef struct {
+	Register string `json:"register"`
+	Field    string `json:"field"`
+}
+
+// (internal)
+const resultRefKey = "$register"
+
+// Native returns the reference as native data, as stored in the arguments of actions
+func (ref ResultRef) Native() map[string]any {
+	return map[string]any{resultRefKey: ref.Register, "field": ref.Field}
+}
+
+// AsResultRef returns the reference held by native data, if any
+func AsResultRef(val any) (ResultRef, bool) {
+	dict, ok := val.(map[string]any)
+
+	if !ok || len(dict) != 2 {
+		return ResultRef{}, false
+	}
+
+	register, ok := dict[resultRefKey].(string)
+	field, found := dict["field"].(string)
+
+	if !ok || !found {
+		return ResultRef{}, false
+	}
+
+	return ResultRef{Register: register, Field: field}, true
+}
+
+// ReplaceResultRefs returns a copy of native data with every reference replaced by the value resolved by fn
+func ReplaceResultRefs(val any, fn func(ref ResultRef) (any, error)) (any, error) {
+	return ReplaceNative(val, func(val any) (any, bool, error) {
+		ref, ok := AsResultRef(val)
+
+		if !ok {
+			return val, false, nil
+		}
+
+		resolved, err := fn(ref)
+		return resolved, true, err
+	})
+}
+
+func (ref ResultRef) String() string {
+	return ref.Register + "." + ref.Field
+}
@@ -0,0 +1,24 @@
+package blueprint_test
+
+import (
+	"testing"
+
+	. "github.com/patrixr/glue/pkg/blueprint"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResultRefs(t *testing.T) {
+	ref := ResultRef{Register: "sh-1", Field: ResultStdout}
+	args := []any{map[string]any{"block": ref.Native(), "path": "~/.zshrc"}}
+
+	resolved, err := ReplaceResultRefs(args, func(ref ResultRef) (any, error) {
+		return Result{Stdout: "/opt/homebrew"}.Field(ref.Field)
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []any{map[string]any{"block": "/opt/homebrew", "path": "~/.zshrc"}}, resolved)
+	assert.Equal(t, ref.Native(), args[0].(map[string]any)["block"], "the arguments are left unchanged")
+
+	holds, _ := Result{RC: 1}.Holds(ResultRC)
+	assert.False(t, holds)
+}
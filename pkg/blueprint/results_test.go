@@ -0,0 +1,65 @@
+package blueprint_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	. "github.com/patrixr/glue/pkg/blueprint"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTraceStatus(t *testing.T) {
+	plan := NewSerialBlueprint("root")
+
+	plan.Action(ActionDef{
+		Name: "ok",
+		Fn: func(ctx context.Context, trace *Trace) error {
+			time.Sleep(5 * time.Millisecond)
+			return nil
+		},
+	})
+
+	plan.Action(ActionDef{
+		Name: "changed",
+		Fn: func(ctx context.Context, trace *Trace) error {
+			trace.Changed = true
+			return nil
+		},
+	})
+
+	plan.Action(ActionDef{
+		Name: "skipped",
+		Fn: func(ctx context.Context, trace *Trace) error {
+			trace.Skip("not needed")
+			return nil
+		},
+	})
+
+	results := plan.Execute(context.Background())
+
+	assert.True(t, results.Success)
+	assert.Equal(t, 0, results.ErrorCount)
+	assert.Equal(t, StatusOk, results.Traces[0].Status)
+	assert.Equal(t, StatusChanged, results.Traces[1].Status)
+	assert.Equal(t, StatusSkipped, results.Traces[2].Status)
+	assert.Equal(t, "not needed", results.Traces[2].Details)
+	assert.GreaterOrEqual(t, results.Traces[0].Duration, 5*time.Millisecond)
+	assert.Equal(t, results.Traces[0].EndTime.Sub(results.Traces[0].StartTime), results.Traces[0].Duration)
+
+	plan.Action(ActionDef{
+		Name: "failed",
+		Fn: func(ctx context.Context, trace *Trace) error {
+			return errors.New("failure")
+		},
+	})
+
+	results = plan.Execute(context.Background())
+
+	assert.False(t, results.Success)
+	assert.Equal(t, 1, results.ErrorCount)
+	assert.Equal(t, StatusFailed, results.Traces[3].Status)
+	assert.Equal(t, 1, results.Count(StatusFailed))
+	assert.Equal(t, 1, results.Count(StatusOk))
+}
@@ -0,0 +1,139 @@
+package blueprint
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// The outcome of an action in a previous run
+type StepState struct {
+	Step       int    `json:"step"`
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	Group      string `json:"group"`
+	Status     Status `json:"status"`
+	Ignored    bool   `json:"ignored,omitempty"`
+	Cancelled  bool   `json:"cancelled,omitempty"`
+	RolledBack bool   `json:"rolled_back,omitempty"`
+}
+
+// The outcome of a run, persisted so that it can be resumed
+// A state only applies to blueprints with the same shape, see Shape
+type RunState struct {
+	Script  string      `json:"script"`
+	Shape   string      `json:"shape"`
+	Time    time.Time   `json:"time"`
+	Success bool        `json:"success"`
+	Steps   []StepState `json:"steps"`
+}
+
+// NewRunState records the outcome of every action of the results
+func NewRunState(script string, blueprint Blueprint, results Results) RunState {
+	state := RunState{
+		Script:  script,
+		Shape:   Shape(blueprint),
+		Time:    time.Now(),
+		Success: results.Success && !results.Stopped,
+	}
+
+	for _, trace := range results.Traces {
+		if trace.Step == 0 {
+			continue
+		}
+
+		state.Steps = append(state.Steps, StepState{
+			Step:       trace.Step,
+			ID:         trace.ID,
+			Name:       trace.Name,
+			Group:      trace.Group,
+			Status:     trace.Status,
+			Ignored:    trace.Ignored,
+			Cancelled:  trace.Cancelled,
+			RolledBack: trace.RolledBack,
+		})
+	}
+
+	return state
+}
+
+// (internal)
+// Checks whether the step has to run again, i.e. it failed, never ran or was reverted
+func (step StepState) unfinished() bool {
+	return (step.Status == StatusFailed && !step.Ignored) || step.Cancelled || step.RolledBack
+}
+
+// FailedSteps returns the steps which failed, never ran or were rolled back
+func (state RunState) FailedSteps() []int {
+	steps := []int{}
+
+	for _, step := range state.Steps {
+		if step.unfinished() {
+			steps = append(steps, step.Step)
+		}
+	}
+
+	return steps
+}
+
+// ResumeStep returns the step to resume the run from, 0 if every step completed
+func (state RunState) ResumeStep() int {
+	steps := state.FailedSteps()
+
+	if len(steps) == 0 {
+		return 0
+	}
+
+	first := steps[0]
+
+	for _, step := range steps {
+		first = min(first, step)
+	}
+
+	return first
+}
+
+// Shape returns a fingerprint of the structure of the blueprint: its groups, actions and their arguments
+// Two blueprints with the same shape number their actions identically
+func Shape(blueprint Blueprint) string {
+	hash := sha256.New()
+
+	var walk func(bp Blueprint, depth int)
+
+	walk = func(bp Blueprint, depth int) {
+		node := serialNode(bp)
+
+		if node == nil {
+			return
+		}
+
+		args, err := json.Marshal(node.Args)
+
+		if err != nil {
+			args = []byte(fmt.Sprint(node.Args))
+		}
+
+		fmt.Fprintf(hash, "%s%s|%s|%s|%s\n", strings.Repeat(" ", depth), node.Name, node.Group, node.Module, args)
+
+		for _, child := range append(node.Children, node.Handlers...) {
+			walk(child, depth+1)
+		}
+	}
+
+	walk(blueprint, 0)
+
+	return hex.EncodeToString(hash.Sum(nil))[:16]
+}
+
+// StepID returns the ID the step had during the run
+func (state RunState) StepID(step int) string {
+	for _, s := range state.Steps {
+		if s.Step == step {
+			return s.ID
+		}
+	}
+	return ""
+}
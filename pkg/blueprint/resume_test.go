@@ -0,0 +1,66 @@
+package blueprint_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	. "github.com/patrixr/glue/pkg/blueprint"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResume(t *testing.T) {
+	var ran []string
+
+	build := func(failing string) *SerialBlueprint {
+		plan := NewSerialBlueprint("root")
+
+		for _, name := range []string{"a", "b", "c"} {
+			plan.Action(ActionDef{
+				Name:   name,
+				Module: "Foo",
+				Args:   []any{name},
+				Fn: func(ctx context.Context, trace *Trace) error {
+					ran = append(ran, name)
+					if name == failing {
+						return errors.New("failed")
+					}
+					return nil
+				},
+			})
+		}
+
+		assert.NoError(t, Resolve(plan, false))
+
+		return plan
+	}
+
+	plan := build("b")
+	state := NewRunState("glue.lua", plan, plan.Execute(context.Background()))
+
+	t.Run("records the failed steps", func(t *testing.T) {
+		assert.False(t, state.Success)
+		assert.Equal(t, []int{2}, state.FailedSteps())
+		assert.Equal(t, 2, state.ResumeStep())
+	})
+
+	t.Run("skips the steps before the resume point", func(t *testing.T) {
+		ran = nil
+		plan := build("")
+		SkipSteps(plan, func(step int) bool { return step < state.ResumeStep() }, "done")
+
+		results := plan.Execute(context.Background())
+
+		assert.Equal(t, []string{"b", "c"}, ran)
+		assert.Equal(t, StatusSkipped, results.Traces[0].Status)
+		assert.Equal(t, 0, NewRunState("glue.lua", plan, results).ResumeStep())
+	})
+
+	t.Run("blueprints with different arguments have different shapes", func(t *testing.T) {
+		other := build("b")
+		assert.Equal(t, Shape(plan), Shape(other))
+
+		other.Children[0].(*SerialBlueprint).Args = []any{"z"}
+		assert.NotEqual(t, Shape(plan), Shape(other))
+	})
+}
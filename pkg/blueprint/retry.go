@@ -0,0 +1,116 @@
+package blueprint
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+)
+
+// Defines how many times a failing action is attempted, and how long to wait between attempts
+type RetryPolicy struct {
+	Attempts int `json:"attempts"`
+	// Time to wait before the second attempt, as a Go duration (e.g. "5s")
+	Delay string `json:"delay,omitempty"`
+	// Factor applied to the delay after every attempt
+	Backoff float64 `json:"backoff,omitempty"`
+}
+
+// A single run of an action retried by its policy
+type Attempt struct {
+	StartTime time.Time     `json:"start_time"`
+	Duration  time.Duration `json:"duration"`
+	Error     error         `json:"error"`
+}
+
+// Validate checks the policy can be applied
+func (policy *RetryPolicy) Validate() error {
+	if policy.Attempts < 1 {
+		return fmt.Errorf("Invalid retry policy, attempts must be at least 1 (got %d)", policy.Attempts)
+	}
+
+	if policy.Backoff < 0 {
+		return fmt.Errorf("Invalid retry policy, backoff cannot be negative (got %v)", policy.Backoff)
+	}
+
+	if len(policy.Delay) > 0 {
+		if _, err := time.ParseDuration(policy.Delay); err != nil {
+			return fmt.Errorf("Invalid retry policy delay '%s'. %w", policy.Delay, err)
+		}
+	}
+
+	return nil
+}
+
+// DelayBefore returns the time to wait before the given attempt (starting at 1)
+func (policy *RetryPolicy) DelayBefore(attempt int) time.Duration {
+	if attempt <= 1 || len(policy.Delay) == 0 {
+		return 0
+	}
+
+	delay, err := time.ParseDuration(policy.Delay)
+
+	if err != nil {
+		return 0
+	}
+
+	backoff := policy.Backoff
+
+	if backoff == 0 {
+		backoff = 1
+	}
+
+	return time.Duration(float64(delay) * math.Pow(backoff, float64(attempt-2)))
+}
+
+// (internal)
+// Runs the function until it succeeds or the attempts of the policy are exhausted
+// The returned trace is the one of the last attempt, listing every attempt made
+// No further attempt is made once the context is cancelled
+func retryTrace(ctx context.Context, policy *RetryPolicy, fn BlueprintFunc) Trace {
+	var trace Trace
+	var attempts []Attempt
+	var undos []func() error
+
+	for attempt := 1; attempt <= policy.Attempts; attempt++ {
+		if attempt > 1 {
+			select {
+			case <-ctx.Done():
+				trace.Attempts = attempts
+				return trace
+			case <-time.After(policy.DelayBefore(attempt)):
+			}
+		}
+
+		trace = fn(ctx)
+		attempts = append(attempts, Attempt{
+			StartTime: trace.StartTime,
+			Duration:  trace.Duration,
+			Error:     trace.Error,
+		})
+
+		if trace.Undo != nil {
+			undos = append(undos, trace.Undo)
+		}
+
+		if trace.Error == nil {
+			break
+		}
+	}
+
+	trace.Attempts = attempts
+
+	// failed attempts may have changed the machine as well
+	if len(undos) > 1 {
+		trace.Undo = func() error {
+			for i := len(undos) - 1; i >= 0; i-- {
+				if err := undos[i](); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	}
+
+	return trace
+}
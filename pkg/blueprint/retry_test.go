@@ -0,0 +1,74 @@
+package blueprint_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	. "github.com/patrixr/glue/pkg/blueprint"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetry(t *testing.T) {
+	t.Run("failing actions are attempted again", func(t *testing.T) {
+		calls := 0
+		plan := NewSerialBlueprint("root")
+
+		plan.Action(ActionDef{
+			Name:  "flaky",
+			Retry: &RetryPolicy{Attempts: 3, Delay: "1ms"},
+			Fn: func(ctx context.Context, trace *Trace) error {
+				calls++
+				if calls < 2 {
+					return errors.New("transient")
+				}
+				return nil
+			},
+		})
+
+		results := plan.Execute(context.Background())
+
+		assert.True(t, results.Success)
+		assert.Equal(t, 2, calls)
+		assert.Len(t, results.Traces[0].Attempts, 2)
+		assert.Error(t, results.Traces[0].Attempts[0].Error)
+		assert.NoError(t, results.Traces[0].Attempts[1].Error)
+	})
+
+	t.Run("the last error is kept when attempts are exhausted", func(t *testing.T) {
+		calls := 0
+		plan := NewSerialBlueprint("root")
+
+		plan.Action(ActionDef{
+			Name:  "broken",
+			Retry: &RetryPolicy{Attempts: 3},
+			Fn: func(ctx context.Context, trace *Trace) error {
+				calls++
+				return errors.New("broken")
+			},
+		})
+
+		results := plan.Execute(context.Background())
+
+		assert.False(t, results.Success)
+		assert.Equal(t, 1, results.ErrorCount)
+		assert.Equal(t, 3, calls)
+		assert.Len(t, results.Traces[0].Attempts, 3)
+	})
+
+	t.Run("delays grow with the backoff", func(t *testing.T) {
+		policy := RetryPolicy{Attempts: 4, Delay: "5s", Backoff: 2}
+
+		assert.Equal(t, time.Duration(0), policy.DelayBefore(1))
+		assert.Equal(t, 5*time.Second, policy.DelayBefore(2))
+		assert.Equal(t, 10*time.Second, policy.DelayBefore(3))
+		assert.Equal(t, 20*time.Second, policy.DelayBefore(4))
+	})
+
+	t.Run("invalid policies are rejected", func(t *testing.T) {
+		assert.Error(t, (&RetryPolicy{Attempts: 0}).Validate())
+		assert.Error(t, (&RetryPolicy{Attempts: 1, Delay: "soon"}).Validate())
+		assert.NoError(t, (&RetryPolicy{Attempts: 2, Delay: "5s", Backoff: 2}).Validate())
+	})
+}
@@ -0,0 +1,88 @@
+package blueprint
+
+import "strings"
+
+// @auteur("Concepts")
+//
+// # Rollback
+//
+// Modules can register undo steps as they run. `Blockinfile` and `Copy`, for instance, restore the previous content of the files they modify.
+// When a run fails, `--rollback-on-failure` replays those steps in reverse order:
+//
+// - `--rollback-on-failure` or `--rollback-on-failure=group` reverts the groups (and their subgroups) in which an action failed
+// - `--rollback-on-failure=run` reverts every action of the run
+//
+// Actions without undo steps, such as `Sh`, are left untouched.
+
+// Defines which actions are reverted when a run fails
+type RollbackPolicy string
+
+const (
+	// Failures are left as is
+	RollbackNone RollbackPolicy = ""
+	// The actions of the groups containing a failure are reverted
+	RollbackGroup RollbackPolicy = "group"
+	// Every action of the run is reverted
+	RollbackRun RollbackPolicy = "run"
+)
+
+func ValidRollbackPolicy(policy string) bool {
+	switch RollbackPolicy(policy) {
+	case RollbackNone, RollbackGroup, RollbackRun:
+		return true
+	}
+	return false
+}
+
+// Rollback replays the undo steps of a failed run in reverse order, according to the policy
+// Actions without an undo step are left untouched, errors raised while undoing are added to the results
+func Rollback(results *Results, policy RollbackPolicy) {
+	if policy == RollbackNone || results.ErrorCount == 0 {
+		return
+	}
+
+	failedGroups := []string{}
+
+	for _, trace := range results.Traces {
+		if trace.Status == StatusFailed && !trace.Ignored {
+			failedGroups = append(failedGroups, trace.Group)
+		}
+	}
+
+	for i := len(results.Traces) - 1; i >= 0; i-- {
+		trace := &results.Traces[i]
+
+		if trace.Undo == nil || trace.RolledBack {
+			continue
+		}
+
+		if policy == RollbackGroup && !inGroups(trace.Group, failedGroups) {
+			continue
+		}
+
+		if err := trace.Undo(); err != nil {
+			trace.RollbackError = err
+			results.ErrorCount++
+			results.Success = false
+			continue
+		}
+
+		trace.RolledBack = true
+	}
+}
+
+// (internal)
+// Checks whether the group is one of the given groups, or nested in one of them
+// Actions declared at the root level only belong to the root group
+func inGroups(group string, groups []string) bool {
+	for _, candidate := range groups {
+		if group == candidate {
+			return true
+		}
+
+		if len(candidate) > 0 && strings.HasPrefix(group, candidate+".") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,69 @@
+package blueprint_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	. "github.com/patrixr/glue/pkg/blueprint"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRollback(t *testing.T) {
+	build := func(undone *[]string) Blueprint {
+		plan := NewSerialBlueprint("root")
+
+		action := func(name string, group string, fail bool) {
+			plan.Action(ActionDef{
+				Name:  name,
+				Group: group,
+				Fn: func(ctx context.Context, trace *Trace) error {
+					trace.Undo = func() error {
+						*undone = append(*undone, name)
+						return nil
+					}
+					if fail {
+						return errors.New("failure")
+					}
+					return nil
+				},
+			})
+		}
+
+		action("a", "first", false)
+		action("b", "second", false)
+		action("c", "second.nested", false)
+		action("d", "second", true)
+
+		return plan
+	}
+
+	t.Run("group policy reverts the failing group in reverse order", func(t *testing.T) {
+		undone := []string{}
+		results := build(&undone).Execute(context.Background())
+
+		Rollback(&results, RollbackGroup)
+
+		assert.Equal(t, []string{"d", "c", "b"}, undone)
+		assert.False(t, results.Traces[0].RolledBack)
+		assert.True(t, results.Traces[1].RolledBack)
+	})
+
+	t.Run("run policy reverts every action", func(t *testing.T) {
+		undone := []string{}
+		results := build(&undone).Execute(context.Background())
+
+		Rollback(&results, RollbackRun)
+
+		assert.Equal(t, []string{"d", "c", "b", "a"}, undone)
+	})
+
+	t.Run("nothing is reverted without a policy", func(t *testing.T) {
+		undone := []string{}
+		results := build(&undone).Execute(context.Background())
+
+		Rollback(&results, RollbackNone)
+
+		assert.Empty(t, undone)
+	})
+}
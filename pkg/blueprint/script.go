@@ -0,0 +1,345 @@
+package blueprint
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Renders an action as POSIX shell commands, for plans exported as scripts
+// The commands of an action run in a subshell with `set -e`, the first failing command fails the action
+type ScriptFunc func() (string, error)
+
+// (internal)
+// The helpers every exported script starts with
+const scriptHelpers = `# Applies the error policy of the enclosing group to the status of a step: continue, stop-group or stop
+glue_failed() {
+  [ "$1" -eq 0 ] && return 0
+  glue_status=1
+  [ -n "$3" ] && echo "glue: $3 failed with status $1" >&2
+  case "$2" in
+    stop) exit 2 ;;
+    stop-group) exit 1 ;;
+  esac
+}
+
+# Groups run in subshells, which exit with 2 when the whole run was stopped
+glue_group_failed() {
+  [ "$1" -eq 2 ] && glue_status=1 && exit 2
+  glue_failed "$1" "$2" ""
+}
+
+# Runs a step again after each of the delays (in seconds), until it succeeds
+glue_retry() {
+  glue_delays=$1
+  shift
+  "$@"
+  glue_rc=$?
+  for glue_delay in $glue_delays; do
+    [ "$glue_rc" -eq 0 ] && break
+    echo "glue: retrying in ${glue_delay}s" >&2
+    sleep "$glue_delay"
+    "$@"
+    glue_rc=$?
+  done
+  return $glue_rc
+}
+
+# Records the handlers notified by a step
+glue_notify() {
+  for glue_handler in "$@"; do
+    echo "$glue_handler" >> "$glue_notify_file"
+  done
+}
+
+glue_was_notified() {
+  grep -qxF "$1" "$glue_notify_file"
+}
+`
+
+// (internal)
+type scriptWriter struct {
+	builder  strings.Builder
+	steps    int
+	problems []string
+}
+
+// ExportScript renders the blueprint as a self-contained POSIX shell script, which applies the plan without glue
+// Every action must be rendered by its module, see ScriptFunc. Conditions only known to glue (functions and registered results)
+// cannot be exported. As scripts don't know whether a step changed anything, handlers run when a step notifying them succeeded
+func ExportScript(root Blueprint) (string, error) {
+	writer := &scriptWriter{}
+	node := serialNode(root)
+
+	if node == nil {
+		return "", fmt.Errorf("Unable to export a blueprint of type %T as a shell script", root)
+	}
+
+	writer.line(0, "#!/bin/sh")
+	writer.line(0, "# Generated by `glue --plan --format sh`, applies the plan without glue")
+	writer.line(0, "set -u")
+	writer.line(0, "")
+	writer.line(0, "glue_status=0")
+	writer.line(0, "glue_notify_file=$(mktemp) || exit 1")
+	writer.line(0, "trap 'rm -f \"$glue_notify_file\"' EXIT")
+	writer.line(0, "")
+	writer.builder.WriteString(scriptHelpers)
+
+	writer.nested(node, node.policy(OnErrorContinue), 0)
+
+	writer.line(0, "")
+	writer.line(0, "exit $glue_status")
+
+	if len(writer.problems) > 0 {
+		return "", fmt.Errorf("Unable to export the plan as a shell script:\n%s", strings.Join(writer.problems, "\n"))
+	}
+
+	return writer.builder.String(), nil
+}
+
+// ShellQuote quotes a string as a single shell word
+func ShellQuote(str string) string {
+	return "'" + strings.ReplaceAll(str, "'", `'\''`) + "'"
+}
+
+// Heredoc feeds the content, followed by a newline, to the standard input of a shell command without expanding it
+// e.g Heredoc("cat > file", "hello") writes hello to the file
+func Heredoc(command string, content string) string {
+	delimiter := "GLUE_EOF"
+
+	for strings.Contains("\n"+content+"\n", "\n"+delimiter+"\n") {
+		delimiter += "_"
+	}
+
+	return fmt.Sprintf("%s <<'%s'\n%s\n%s", command, delimiter, content, delimiter)
+}
+
+// (internal)
+// Returns the error policy of the node, given the one of its enclosing group
+func (blueprint *SerialBlueprint) policy(inherited ErrorPolicy) ErrorPolicy {
+	if blueprint.OnError != OnErrorInherit {
+		return blueprint.OnError
+	}
+	return inherited
+}
+
+// (internal)
+func (writer *scriptWriter) line(depth int, format string, args ...any) {
+	if len(format) > 0 {
+		writer.builder.WriteString(strings.Repeat("  ", depth))
+	}
+	if len(args) > 0 {
+		format = fmt.Sprintf(format, args...)
+	}
+	writer.builder.WriteString(format + "\n")
+}
+
+// (internal)
+// Writes the children of a group followed by its handlers, the policy applies to their failures
+func (writer *scriptWriter) nested(node *SerialBlueprint, policy ErrorPolicy, depth int) {
+	for _, child := range node.Children {
+		writer.child(child, policy, depth)
+	}
+
+	for _, handler := range node.Handlers {
+		writer.child(handler, policy, depth)
+	}
+}
+
+// (internal)
+func (writer *scriptWriter) child(bp Blueprint, policy ErrorPolicy, depth int) {
+	node := serialNode(bp)
+
+	if node == nil {
+		writer.problem(bp.Info(), fmt.Sprintf("blueprints of type %T cannot be exported", bp))
+		return
+	}
+
+	if node.isAction() {
+		writer.action(node, policy, depth)
+	} else {
+		writer.group(node, bp.Info().Parallel, policy, depth)
+	}
+}
+
+// (internal)
+// Groups run in a subshell, exiting it stops the group. Handlers only run once notified
+func (writer *scriptWriter) group(node *SerialBlueprint, parallel bool, policy ErrorPolicy, depth int) {
+	writer.line(0, "")
+
+	label := node.Group
+	if len(label) == 0 {
+		label = node.Name
+	}
+
+	switch {
+	case node.Handler:
+		writer.line(depth, "# Handler %s", node.Name)
+		writer.line(depth, "if glue_was_notified %s; then", ShellQuote(strings.ToLower(node.Name)))
+		depth++
+	case parallel:
+		writer.line(depth, "# Group %s (parallel in glue, the script runs its steps one after the other)", label)
+	default:
+		writer.line(depth, "# Group %s", label)
+	}
+
+	depth = writer.openCondition(node, depth)
+
+	writer.line(depth, "(")
+	writer.line(depth+1, "glue_status=0")
+	writer.nested(node, node.policy(policy), depth+1)
+	writer.line(0, "")
+	writer.line(depth+1, "exit $glue_status")
+	writer.line(depth, ")")
+	writer.line(depth, "glue_group_failed $? %s", policy.scriptName())
+
+	writer.closeCondition(node, depth)
+
+	if node.Handler {
+		writer.line(depth-1, "fi")
+	}
+}
+
+// (internal)
+// Actions are rendered as a function, called according to their options
+func (writer *scriptWriter) action(node *SerialBlueprint, policy ErrorPolicy, depth int) {
+	info := node.Info()
+	label := info.ID
+	if len(label) == 0 {
+		label = info.Name
+	}
+
+	writer.steps++
+	fn := fmt.Sprintf("glue_step_%d", writer.steps)
+
+	writer.line(0, "")
+
+	if len(info.Source) > 0 {
+		writer.line(depth, "# [%s] %s (%s)", label, info.Name, info.Source)
+	} else {
+		writer.line(depth, "# [%s] %s", label, info.Name)
+	}
+
+	if len(node.Timeout) > 0 {
+		writer.line(depth, "# The %s timeout is not enforced by the script", node.Timeout)
+	}
+
+	if node.ScriptFunction == nil {
+		writer.problem(info, fmt.Sprintf("%s has no shell rendering", info.Name))
+		return
+	}
+
+	commands, err := node.ScriptFunction()
+
+	if err != nil {
+		writer.problem(info, err.Error())
+		return
+	}
+
+	if len(strings.TrimSpace(commands)) == 0 {
+		commands = ":"
+	}
+
+	// the lines of heredocs are kept as they are
+	indent := depth + 1
+	if strings.Contains(commands, "<<") {
+		indent = 0
+	}
+
+	writer.line(depth, "%s() (", fn)
+	writer.line(depth+1, "set -e")
+	for _, command := range strings.Split(strings.TrimSuffix(commands, "\n"), "\n") {
+		writer.line(indent, "%s", command)
+	}
+	writer.line(depth, ")")
+
+	depth = writer.openCondition(node, depth)
+
+	// steps are never called within a list (e.g `step && ...`), which would disable their `set -e`
+	if node.Retry != nil && node.Retry.Attempts > 1 {
+		writer.line(depth, "glue_retry %s %s", ShellQuote(node.Retry.scriptDelays()), fn)
+	} else {
+		writer.line(depth, "%s", fn)
+	}
+
+	writer.line(depth, "glue_rc=$?")
+
+	if len(node.Notify) > 0 {
+		handlers := make([]string, len(node.Notify))
+		for i, name := range node.Notify {
+			handlers[i] = ShellQuote(strings.ToLower(name))
+		}
+		writer.line(depth, "[ $glue_rc -eq 0 ] && glue_notify %s", strings.Join(handlers, " "))
+	}
+
+	if node.IgnoreErrors {
+		writer.line(depth, "[ $glue_rc -eq 0 ] || echo %s >&2", ShellQuote(fmt.Sprintf("glue: %s failed, ignored", label)))
+	} else {
+		writer.line(depth, "glue_failed $glue_rc %s %s", policy.scriptName(), ShellQuote(label))
+	}
+
+	writer.closeCondition(node, depth)
+}
+
+// (internal)
+// Opens an if statement for nodes with a shell condition, returning the depth of its body
+func (writer *scriptWriter) openCondition(node *SerialBlueprint, depth int) int {
+	if node.When == nil {
+		return depth
+	}
+
+	if len(node.When.Shell) == 0 {
+		writer.problem(node.Info(), fmt.Sprintf("the condition %s cannot be evaluated by a script, use a shell test instead", node.When.Source()))
+		return depth
+	}
+
+	writer.line(depth, "if { %s; } >/dev/null 2>&1; then", node.When.Shell)
+	return depth + 1
+}
+
+// (internal)
+func (writer *scriptWriter) closeCondition(node *SerialBlueprint, depth int) {
+	if node.When != nil && len(node.When.Shell) > 0 {
+		writer.line(depth-1, "fi")
+	}
+}
+
+// (internal)
+// e.g `- configs.nvim [copy-1] (glue.lua:12): Copy has no shell rendering`
+func (writer *scriptWriter) problem(node Node, problem string) {
+	group := node.Group
+
+	if len(group) == 0 {
+		group = "root"
+	}
+
+	if len(node.ID) > 0 {
+		group = fmt.Sprintf("%s [%s]", group, node.ID)
+	}
+
+	if len(node.Source) > 0 {
+		group = fmt.Sprintf("%s (%s)", group, node.Source)
+	}
+
+	writer.problems = append(writer.problems, fmt.Sprintf("  - %s: %s", group, problem))
+}
+
+// (internal)
+func (policy ErrorPolicy) scriptName() string {
+	if policy == OnErrorInherit {
+		return string(OnErrorContinue)
+	}
+	return string(policy)
+}
+
+// (internal)
+// Returns the delays before each retry in whole seconds, as expected by sleep
+func (policy *RetryPolicy) scriptDelays() string {
+	delays := []string{}
+
+	for attempt := 2; attempt <= policy.Attempts; attempt++ {
+		delays = append(delays, fmt.Sprintf("%d", int(math.Ceil(policy.DelayBefore(attempt).Seconds()))))
+	}
+
+	return strings.Join(delays, " ")
+}
@@ -0,0 +1,137 @@
+package blueprint_test
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+	"testing"
+
+	. "github.com/patrixr/glue/pkg/blueprint"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExportScript(t *testing.T) {
+	action := func(name string, group string, commands string) ActionDef {
+		return ActionDef{
+			Name:   name,
+			Group:  group,
+			Module: "Sh",
+			Fn:     func(ctx context.Context, trace *Trace) error { return nil },
+			Script: func() (string, error) { return commands, nil },
+		}
+	}
+
+	run := func(t *testing.T, root Blueprint) (string, int) {
+		script, err := ExportScript(root)
+		assert.NoError(t, err)
+
+		output, err := exec.Command("sh", "-c", script).CombinedOutput()
+
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return string(output), exitErr.ExitCode()
+		}
+
+		assert.NoError(t, err)
+		return string(output), 0
+	}
+
+	t.Run("applies the error policies of the groups", func(t *testing.T) {
+		root := NewSerialBlueprint("root")
+		group := NewSerialBlueprint("group")
+		group.Group = "group"
+		group.OnError = OnErrorStopGroup
+		group.Action(action("a", "group", "echo a"))
+		group.Action(action("b", "group", "false\necho not printed"))
+		group.Action(action("c", "group", "echo c"))
+		root.Add(group)
+		root.Action(action("d", "", "echo d"))
+
+		output, status := run(t, root)
+
+		assert.Equal(t, "a\nglue: b failed with status 1\nd\n", output)
+		assert.Equal(t, 1, status)
+	})
+
+	t.Run("stops the run", func(t *testing.T) {
+		root := NewSerialBlueprint("root")
+		group := NewSerialBlueprint("group")
+		group.Group = "group"
+		group.OnError = OnErrorStop
+		group.Action(action("a", "group", "exit 3"))
+		root.Add(group)
+		root.Action(action("b", "", "echo b"))
+
+		output, status := run(t, root)
+
+		assert.NotContains(t, output, "b\n")
+		assert.Equal(t, 2, status)
+	})
+
+	t.Run("runs shell conditions and notified handlers", func(t *testing.T) {
+		root := NewSerialBlueprint("root")
+
+		notifier := action("a", "", "echo a")
+		notifier.Notify = []string{"Reload"}
+		root.Action(notifier)
+
+		skipped := action("b", "", "echo b")
+		skipped.When = &Condition{Shell: "test -z notempty"}
+		root.Action(skipped)
+
+		handler := NewSerialBlueprint("reload")
+		handler.Action(action("c", "reload", "echo reloaded"))
+		root.AddHandler(handler)
+
+		unnotified := NewSerialBlueprint("other")
+		unnotified.Action(action("d", "other", "echo other"))
+		root.AddHandler(unnotified)
+
+		output, status := run(t, root)
+
+		assert.Equal(t, "a\nreloaded\n", output)
+		assert.Equal(t, 0, status)
+	})
+
+	t.Run("ignores failures and retries actions", func(t *testing.T) {
+		root := NewSerialBlueprint("root")
+
+		ignored := action("a", "", "false")
+		ignored.IgnoreErrors = true
+		root.Action(ignored)
+
+		retried := action("b", "", "echo attempt\nfalse")
+		retried.Retry = &RetryPolicy{Attempts: 2}
+		root.Action(retried)
+
+		output, status := run(t, root)
+
+		assert.Contains(t, output, "failed, ignored")
+		assert.Equal(t, 2, strings.Count(output, "attempt\n"))
+		assert.Equal(t, 1, status)
+	})
+
+	t.Run("fails for actions without a rendering", func(t *testing.T) {
+		root := NewSerialBlueprint("root")
+		root.Action(ActionDef{Name: "Copy", Group: "configs", Module: "Copy", Source: "glue.lua:3"})
+
+		conditional := action("b", "", "echo b")
+		conditional.When = &Condition{Eval: func(ctx context.Context) (bool, error) { return true, nil }}
+		root.Action(conditional)
+
+		_, err := ExportScript(root)
+
+		assert.ErrorContains(t, err, "configs (glue.lua:3): Copy has no shell rendering")
+		assert.ErrorContains(t, err, "the condition <function> cannot be evaluated by a script")
+	})
+}
+
+func TestHeredoc(t *testing.T) {
+	assert.Equal(t, "cat <<'GLUE_EOF'\nhello\nGLUE_EOF", Heredoc("cat", "hello"))
+	assert.Equal(t, "cat <<'GLUE_EOF_'\nGLUE_EOF\nGLUE_EOF_", Heredoc("cat", "GLUE_EOF"))
+
+	output, err := exec.Command("sh", "-c", Heredoc("cat", "it's $HOME")).Output()
+
+	assert.NoError(t, err)
+	assert.Equal(t, "it's $HOME\n", string(output))
+	assert.Equal(t, `'it'\''s'`, ShellQuote("it's"))
+}
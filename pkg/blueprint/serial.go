@@ -1,13 +1,50 @@
 package blueprint
 
-import "strings"
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
 
 type SerialBlueprint struct {
-	Name       string      `json:"name"`
-	Details    string      `json:"details"`
-	Annotation string      `json:"annotation"`
-	Children   []Blueprint `json:"children"`
-	Function   BlueprintFunc
+	// Identifies the action, set by Resolve
+	ID         string       `json:"id,omitempty"`
+	Name       string       `json:"name"`
+	Details    string       `json:"details"`
+	Annotation string       `json:"annotation"`
+	Group      string       `json:"group,omitempty"`
+	Module     string       `json:"module,omitempty"`
+	Args       []any        `json:"args,omitempty"`
+	DependsOn  []string     `json:"depends_on,omitempty"`
+	Retry      *RetryPolicy `json:"retry,omitempty"`
+	Notify     []string     `json:"notify,omitempty"`
+	When       *Condition   `json:"when,omitempty"`
+	OnError    ErrorPolicy  `json:"on_error,omitempty"`
+	// Failures of the action don't fail the run
+	IgnoreErrors bool          `json:"ignore_errors,omitempty"`
+	Timeout      string        `json:"timeout,omitempty"`
+	Phase        Phase         `json:"phase,omitempty"`
+	Register     string        `json:"register,omitempty"`
+	Source       string        `json:"source,omitempty"`
+	Dir          string        `json:"dir,omitempty"`
+	Merged       []MergedCall  `json:"merged,omitempty"`
+	Children     []Blueprint   `json:"children"`
+	Handlers     []Blueprint   `json:"handlers,omitempty"`
+	Handler      bool          `json:"handler,omitempty"`
+	Function     BlueprintFunc `json:"-"`
+	// Runs the check of the action, set when the module supports it
+	CheckFunction BlueprintFunc `json:"-"`
+	// Renders the action as shell commands, set when the module supports it
+	ScriptFunction ScriptFunc `json:"-"`
+
+	// indices of the children each child has to wait for, set by Resolve
+	waits [][]int
+	// the error policy of the enclosing group, set before running
+	inheritedPolicy ErrorPolicy
+	// the position of the action in execution order, set by Resolve
+	step int
 }
 
 func NewSerialBlueprint(name string) *SerialBlueprint {
@@ -17,48 +54,116 @@ func NewSerialBlueprint(name string) *SerialBlueprint {
 	}
 }
 
-func (blueprint *SerialBlueprint) Execute() Results {
-	results := Results{}
+func (blueprint *SerialBlueprint) Execute(ctx context.Context) Results {
+	return blueprint.run(ctx, modeExecute)
+}
 
-	if blueprint.Function != nil {
-		trace := blueprint.Function()
+// Check runs the checks of every action without side effects
+// The resulting traces report whether each action would change anything
+func (blueprint *SerialBlueprint) Check(ctx context.Context) Results {
+	return blueprint.run(ctx, modeCheck)
+}
 
-		if trace.Error != nil {
-			results.ErrorCount++
-			results.Success = false
+func (blueprint *SerialBlueprint) Action(action ActionDef) {
+	node := &SerialBlueprint{
+		Name:         action.Name,
+		Details:      action.Details,
+		Annotation:   action.Annotation,
+		Group:        action.Group,
+		Module:       action.Module,
+		Args:         action.Args,
+		Retry:        action.Retry,
+		Notify:       action.Notify,
+		When:         action.When,
+		IgnoreErrors: action.IgnoreErrors,
+		Timeout:      action.Timeout,
+		Phase:        action.Phase,
+		Register:     action.Register,
+		Source:       action.Source,
+		Dir:          action.Dir,
+		Merged:       action.Merged,
+		Children:     []Blueprint{},
+	}
+
+	node.bindFunctions(action)
+
+	blueprint.Children = append(blueprint.Children, node)
+}
+
+func (blueprint *SerialBlueprint) Add(subBlueprint Blueprint) {
+	blueprint.Children = append(blueprint.Children, subBlueprint)
+}
+
+// Bind attaches an executable function to every action of the blueprint
+// This is required for blueprints loaded from a file, which only hold the actions as data
+func (blueprint *SerialBlueprint) Bind(binder ActionBinder) error {
+	if len(blueprint.Module) > 0 {
+		action, err := binder(blueprint.actionDef())
+
+		if err != nil {
+			return fmt.Errorf("Unable to load action %s: %w", blueprint.Name, err)
 		}
 
-		results.Traces = append(results.Traces, trace)
+		blueprint.Dir = action.Dir
+		blueprint.bindFunctions(action)
+	} else if blueprint.When != nil {
+		group, err := binder(blueprint.actionDef())
+
+		if err != nil {
+			return fmt.Errorf("Unable to load group %s: %w", blueprint.Name, err)
+		}
+
+		blueprint.When = group.When
 	}
 
-	for _, child := range blueprint.Children {
-		res := child.Execute()
-		results.Traces = append(results.Traces, res.Traces...)
-		results.ErrorCount += res.ErrorCount
-		results.Success = results.Success && res.Success
+	for _, child := range append(blueprint.Children, blueprint.Handlers...) {
+		if err := child.Bind(binder); err != nil {
+			return err
+		}
 	}
 
-	return results
+	return nil
 }
 
-func (blueprint *SerialBlueprint) Action(name string, details string, usertext string, fn ActionFunc) {
-	blueprint.Children = append(blueprint.Children, &SerialBlueprint{
-		Function: func() Trace {
-			err := fn()
-			return Trace{
-				Name:  name,
-				Error: err,
-			}
-		},
-		Name:       name,
-		Details:    details,
-		Annotation: usertext,
-		Children:   []Blueprint{},
-	})
-}
+func (blueprint *SerialBlueprint) UnmarshalJSON(data []byte) error {
+	type serialFields SerialBlueprint
 
-func (blueprint *SerialBlueprint) Add(subBlueprint Blueprint) {
-	blueprint.Children = append(blueprint.Children, subBlueprint)
+	var raw struct {
+		serialFields
+		Children []json.RawMessage `json:"children"`
+		Handlers []json.RawMessage `json:"handlers"`
+	}
+
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	*blueprint = SerialBlueprint(raw.serialFields)
+	blueprint.Children = []Blueprint{}
+
+	for _, data := range raw.Children {
+		child, err := decodeBlueprint(data)
+
+		if err != nil {
+			return err
+		}
+
+		blueprint.Children = append(blueprint.Children, child)
+	}
+
+	blueprint.Handlers = nil
+
+	for _, data := range raw.Handlers {
+		handler, err := decodeBlueprint(data)
+
+		if err != nil {
+			return err
+		}
+
+		blueprint.Handlers = append(blueprint.Handlers, handler)
+	}
+
+	return nil
 }
 
 func (blueprint *SerialBlueprint) PrettyPrint() string {
@@ -73,11 +178,253 @@ func (blueprint *SerialBlueprint) prettyPrintRecursive(builder *strings.Builder,
 		builder.WriteString("  ")
 	}
 	builder.WriteString("+ ")
-	builder.WriteString(blueprint.Name)
+	builder.WriteString(blueprint.describe())
 	builder.WriteString("\n")
 
-	for _, child := range blueprint.Children {
-		childBlueprint := child.(*SerialBlueprint)
-		childBlueprint.prettyPrintRecursive(builder, depth+1)
+	for _, child := range blueprint.Nested() {
+		prettyPrintChild(builder, child, depth+1)
+	}
+}
+
+// (internal)
+// Blueprints implemented outside of this package are indented from their own output
+func prettyPrintChild(builder *strings.Builder, child Blueprint, depth int) {
+	if printer, ok := child.(prettyPrinter); ok {
+		printer.prettyPrintRecursive(builder, depth)
+		return
+	}
+
+	for _, line := range strings.SplitAfter(child.PrettyPrint(), "\n") {
+		if len(line) > 0 {
+			builder.WriteString(strings.Repeat("  ", depth) + line)
+		}
+	}
+}
+
+// (internal)
+func (blueprint *SerialBlueprint) describe() string {
+	description := blueprint.Name
+
+	if len(blueprint.ID) > 0 {
+		description += fmt.Sprintf(" [%s]", blueprint.ID)
+	}
+
+	if blueprint.Handler {
+		description += " (handler)"
+	}
+
+	if blueprint.isPhase() {
+		description += " (phase)"
+	}
+
+	if blueprint.When != nil {
+		description += fmt.Sprintf(" (%s)", blueprint.When)
+	}
+
+	if len(blueprint.DependsOn) > 0 {
+		description += fmt.Sprintf(" (after %s)", strings.Join(blueprint.DependsOn, ", "))
+	}
+
+	return description
+}
+
+// (internal)
+type runMode int
+
+const (
+	modeExecute runMode = iota
+	modeCheck
+)
+
+// (internal)
+func (blueprint *SerialBlueprint) run(ctx context.Context, mode runMode) Results {
+	if results, ok := blueprint.guard(ctx); !ok {
+		return results
+	}
+
+	start := time.Now()
+	results := blueprint.runFunction(ctx, mode)
+
+	for i, child := range blueprint.Children {
+		if ctx.Err() != nil {
+			results.Stopped = true
+
+			for _, rest := range blueprint.Children[i:] {
+				results.Merge(cancelledResults(rest, interruptedReason))
+			}
+			break
+		}
+
+		blueprint.inherit(child)
+
+		res := runBlueprint(ctx, child, mode)
+		results.Merge(res)
+
+		if interrupted, stopped := blueprint.interrupted(res); interrupted {
+			results.Stopped = results.Stopped || stopped
+
+			for _, rest := range blueprint.Children[i+1:] {
+				results.Merge(cancelledResults(rest, blueprint.interruptReason(stopped)))
+			}
+			break
+		}
+	}
+
+	results.Merge(blueprint.runHandlers(ctx, results, mode))
+
+	results.TimeElapsedSec = int(time.Since(start).Seconds())
+
+	return results
+}
+
+// (internal)
+// Runs the function of the blueprint itself, if any
+func (blueprint *SerialBlueprint) runFunction(ctx context.Context, mode runMode) Results {
+	results := Results{Success: true}
+	fn := blueprint.Function
+
+	if mode == modeCheck {
+		fn = blueprint.CheckFunction
+
+		if fn == nil && blueprint.Function != nil {
+			// without a check we cannot guarantee the action is a no-op
+			fn = uncheckedTrace(blueprint.actionDef())
+		}
+	}
+
+	if fn != nil && mode == modeExecute {
+		if approved, reason := blueprint.approved(ctx); !approved {
+			return blueprint.skippedResults(reason)
+		}
+	}
+
+	if fn != nil {
+		var trace Trace
+
+		if mode == modeExecute && blueprint.Retry != nil {
+			trace = retryTrace(ctx, blueprint.Retry, fn)
+		} else {
+			trace = fn(ctx)
+		}
+
+		trace.ID = blueprint.ID
+		trace.Step = blueprint.step
+
+		if trace.Error != nil && !trace.Ignored {
+			results.ErrorCount++
+			results.Success = false
+		}
+
+		results.Traces = append(results.Traces, trace)
+	}
+
+	return results
+}
+
+// (internal)
+func (blueprint *SerialBlueprint) bindFunctions(action ActionDef) {
+	blueprint.Function = nil
+	blueprint.CheckFunction = nil
+	blueprint.ScriptFunction = action.Script
+
+	if action.Fn != nil {
+		blueprint.Function = actionTrace(action, action.Fn)
+	}
+
+	if action.Check != nil {
+		blueprint.CheckFunction = actionTrace(action, action.Check)
+	}
+}
+
+// (internal)
+func runBlueprint(ctx context.Context, blueprint Blueprint, mode runMode) Results {
+	if mode == modeCheck {
+		return blueprint.Check(ctx)
+	}
+	return blueprint.Execute(ctx)
+}
+
+// (internal)
+func (blueprint *SerialBlueprint) actionDef() ActionDef {
+	return ActionDef{
+		Name:         blueprint.Name,
+		Details:      blueprint.Details,
+		Annotation:   blueprint.Annotation,
+		Group:        blueprint.Group,
+		Module:       blueprint.Module,
+		Args:         blueprint.Args,
+		Retry:        blueprint.Retry,
+		Notify:       blueprint.Notify,
+		When:         blueprint.When,
+		IgnoreErrors: blueprint.IgnoreErrors,
+		Timeout:      blueprint.Timeout,
+		Phase:        blueprint.Phase,
+		Register:     blueprint.Register,
+		Source:       blueprint.Source,
+		Dir:          blueprint.Dir,
+		Merged:       blueprint.Merged,
+	}
+}
+
+// (internal)
+type prettyPrinter interface {
+	prettyPrintRecursive(builder *strings.Builder, depth int)
+}
+
+// (internal)
+func actionTrace(action ActionDef, fn ActionFunc) BlueprintFunc {
+	return func(ctx context.Context) Trace {
+		trace := Trace{
+			Name:      action.Name,
+			Details:   action.Details,
+			Group:     action.Group,
+			Notify:    action.Notify,
+			Merged:    action.Merged,
+			StartTime: time.Now(),
+		}
+
+		actionCtx, cancel := withTimeout(ctx, action.Timeout)
+		defer cancel()
+
+		trace.Error = fn(actionCtx, &trace)
+
+		if trace.Error != nil && ctx.Err() != nil {
+			trace.Error = fmt.Errorf("Interrupted: %w", trace.Error)
+		} else if trace.Error != nil && actionCtx.Err() != nil {
+			trace.Error = fmt.Errorf("Timed out after %s: %w", action.Timeout, trace.Error)
+		}
+		trace.Ignored = trace.Error != nil && action.IgnoreErrors
+		trace.EndTime = time.Now()
+		trace.Duration = trace.EndTime.Sub(trace.StartTime)
+		trace.Status = traceStatus(trace)
+		return trace
+	}
+}
+
+// (internal)
+func traceStatus(trace Trace) Status {
+	switch {
+	case trace.Error != nil:
+		return StatusFailed
+	case trace.Status == StatusSkipped:
+		return StatusSkipped
+	case trace.Changed:
+		return StatusChanged
+	default:
+		return StatusOk
+	}
+}
+
+// (internal)
+func uncheckedTrace(action ActionDef) BlueprintFunc {
+	return func(ctx context.Context) Trace {
+		return Trace{
+			Name:    action.Name,
+			Group:   action.Group,
+			Details: "No check available, assuming changes",
+			Changed: true,
+			Status:  StatusChanged,
+			Notify:  action.Notify,
+		}
 	}
 }
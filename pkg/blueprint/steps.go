@@ -0,0 +1,139 @@
+package blueprint
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// (internal)
+func (blueprint *SerialBlueprint) isAction() bool {
+	return blueprint.Function != nil || len(blueprint.Module) > 0
+}
+
+// (internal)
+// Returns the actions of the blueprint in execution order, handlers last
+func actionNodes(blueprint Blueprint) []*SerialBlueprint {
+	nodes := []*SerialBlueprint{}
+
+	var walk func(bp Blueprint)
+
+	walk = func(bp Blueprint) {
+		node := serialNode(bp)
+
+		if node == nil {
+			return
+		}
+
+		if node.isAction() {
+			nodes = append(nodes, node)
+		}
+
+		for _, child := range append(node.Children, node.Handlers...) {
+			walk(child)
+		}
+	}
+
+	walk(blueprint)
+
+	return nodes
+}
+
+// (internal)
+// Numbers the actions of the blueprint in execution order, starting from 1, and assigns their IDs
+func numberSteps(blueprint Blueprint) {
+	for i, node := range actionNodes(blueprint) {
+		node.step = i + 1
+	}
+
+	var walk func(bp Blueprint)
+
+	walk = func(bp Blueprint) {
+		node := serialNode(bp)
+
+		if node == nil {
+			return
+		}
+
+		position := 0
+
+		for _, child := range append(node.Children, node.Handlers...) {
+			if action := serialNode(child); action != nil && action.isAction() {
+				position++
+				action.ID = actionID(action, position)
+			}
+
+			walk(child)
+		}
+	}
+
+	walk(blueprint)
+}
+
+// (internal)
+// Builds the ID of an action from its group path, its position within the group and its arguments
+// e.g. `configs.nvim/copy-2-3f9a1c`
+func actionID(action *SerialBlueprint, position int) string {
+	args, err := json.Marshal(action.Args)
+
+	if err != nil {
+		args = []byte(fmt.Sprint(action.Args))
+	}
+
+	hash := sha256.Sum256(args)
+	id := fmt.Sprintf("%s-%d-%s", strings.ToLower(action.Name), position, hex.EncodeToString(hash[:3]))
+
+	if len(action.Group) > 0 {
+		id = action.Group + "/" + id
+	}
+
+	return id
+}
+
+// FindStep returns the step of the action with the given ID, 0 if there is none
+func FindStep(blueprint Blueprint, id string) int {
+	for _, node := range actionNodes(blueprint) {
+		if strings.EqualFold(node.ID, id) {
+			return node.step
+		}
+	}
+	return 0
+}
+
+// KeepSteps removes the actions not matching the predicate, and the handlers, from the blueprint
+// Steps are the positions of the actions in execution order, as numbered by Resolve
+func KeepSteps(blueprint Blueprint, keep func(step int) bool) {
+	Filter(blueprint, func(node Node) bool {
+		return !node.Handler && (!node.Action || keep(node.Step))
+	})
+}
+
+// SkipSteps replaces the actions matching the predicate with a skipped trace, without running them
+// Steps are the positions of the actions in execution order, as numbered by Resolve
+func SkipSteps(blueprint Blueprint, skip func(step int) bool, reason string) {
+	for _, node := range actionNodes(blueprint) {
+		if !skip(node.step) {
+			continue
+		}
+
+		trace := Trace{
+			ID:      node.ID,
+			Name:    node.Name,
+			Group:   node.Group,
+			Step:    node.step,
+			Status:  StatusSkipped,
+			Details: reason,
+		}
+
+		skipped := func(ctx context.Context) Trace {
+			return trace
+		}
+
+		node.Function = skipped
+		node.CheckFunction = skipped
+		node.Retry = nil
+	}
+}
@@ -0,0 +1,68 @@
+package blueprint_test
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/patrixr/glue/pkg/blueprint"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestActionIDs(t *testing.T) {
+	var ran []any
+
+	build := func(extra bool) *SerialBlueprint {
+		plan := NewSerialBlueprint("root")
+		group := NewSerialBlueprint("configs")
+		group.Group = "configs"
+
+		for _, dest := range []string{"a", "b"} {
+			group.Action(ActionDef{
+				Name:   "Copy",
+				Group:  "configs",
+				Module: "Copy",
+				Args:   []any{dest},
+				Fn: func(ctx context.Context, trace *Trace) error {
+					ran = append(ran, dest)
+					return nil
+				},
+			})
+		}
+
+		if extra {
+			plan.Action(ActionDef{Name: "Sh", Module: "Sh", Args: []any{"echo"}})
+		}
+
+		plan.Add(group)
+
+		assert.NoError(t, Resolve(plan, false))
+
+		return plan
+	}
+
+	plan := build(false)
+	action := plan.Children[0].(*SerialBlueprint).Children[1].(*SerialBlueprint)
+
+	t.Run("are built from the group, position and arguments", func(t *testing.T) {
+		assert.Regexp(t, `^configs/copy-2-[0-9a-f]{6}$`, action.ID)
+		assert.Contains(t, plan.PrettyPrint(), "+ Copy ["+action.ID+"]")
+	})
+
+	t.Run("don't depend on actions of other groups", func(t *testing.T) {
+		other := build(true)
+		assert.Equal(t, 3, FindStep(other, action.ID))
+		assert.Equal(t, 2, FindStep(plan, action.ID))
+	})
+
+	t.Run("select a single action to run", func(t *testing.T) {
+		ran = nil
+		step := FindStep(plan, action.ID)
+		KeepSteps(plan, func(s int) bool { return s == step })
+
+		results := plan.Execute(context.Background())
+
+		assert.Equal(t, []any{"b"}, ran)
+		assert.Len(t, results.Traces, 1)
+		assert.Equal(t, action.ID, results.Traces[0].ID)
+	})
+}
@@ -0,0 +1,38 @@
+package blueprint
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// (internal)
+const interruptedReason = "Not run, the run was interrupted"
+
+// ParseTimeout reads the timeout of an action, e.g. "10m"
+// An empty timeout means the action can run for as long as it needs
+func ParseTimeout(timeout string) (time.Duration, error) {
+	if len(timeout) == 0 {
+		return 0, nil
+	}
+
+	duration, err := time.ParseDuration(timeout)
+
+	if err != nil || duration <= 0 {
+		return 0, fmt.Errorf("Invalid timeout '%s', expected a duration such as \"10m\"", timeout)
+	}
+
+	return duration, nil
+}
+
+// (internal)
+// Returns the context an action runs in, cancelled once its timeout expires
+func withTimeout(ctx context.Context, timeout string) (context.Context, context.CancelFunc) {
+	duration, err := ParseTimeout(timeout)
+
+	if err != nil || duration == 0 {
+		return context.WithCancel(ctx)
+	}
+
+	return context.WithTimeout(ctx, duration)
+}
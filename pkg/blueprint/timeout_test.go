@@ -0,0 +1,83 @@
+package blueprint_test
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/patrixr/glue/pkg/blueprint"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCancellation(t *testing.T) {
+	wait := func(ctx context.Context, trace *Trace) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	t.Run("actions are cancelled once their timeout expires", func(t *testing.T) {
+		plan := NewSerialBlueprint("root")
+		plan.Action(ActionDef{Name: "hung", Timeout: "10ms", Fn: wait})
+
+		results := plan.Execute(context.Background())
+
+		assert.False(t, results.Success)
+		assert.ErrorContains(t, results.Traces[0].Error, "Timed out after 10ms")
+	})
+
+	t.Run("the remaining steps are skipped once the run is interrupted", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		ran := []string{}
+
+		plan := NewSerialBlueprint("root")
+		plan.Action(ActionDef{
+			Name: "interrupted",
+			Fn: func(ctx context.Context, trace *Trace) error {
+				ran = append(ran, "interrupted")
+				cancel()
+				return wait(ctx, trace)
+			},
+		})
+
+		group := NewSerialBlueprint("group")
+		group.Action(ActionDef{
+			Name: "never",
+			Fn: func(ctx context.Context, trace *Trace) error {
+				ran = append(ran, "never")
+				return nil
+			},
+		})
+		plan.Add(group)
+
+		results := plan.Execute(ctx)
+
+		assert.Equal(t, []string{"interrupted"}, ran)
+		assert.True(t, results.Stopped)
+		assert.ErrorContains(t, results.Traces[0].Error, "Interrupted")
+		assert.True(t, results.Traces[1].Cancelled)
+	})
+
+	t.Run("interrupted actions are not retried", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		attempts := 0
+
+		plan := NewSerialBlueprint("root")
+		plan.Action(ActionDef{
+			Name:  "flaky",
+			Retry: &RetryPolicy{Attempts: 3, Delay: "1h"},
+			Fn: func(ctx context.Context, trace *Trace) error {
+				attempts++
+				cancel()
+				return wait(ctx, trace)
+			},
+		})
+
+		plan.Execute(ctx)
+
+		assert.Equal(t, 1, attempts)
+	})
+
+	t.Run("timeouts must be durations", func(t *testing.T) {
+		_, err := ParseTimeout("soon")
+		assert.Error(t, err)
+	})
+}
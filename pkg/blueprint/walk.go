@@ -0,0 +1,234 @@
+package blueprint
+
+import "errors"
+
+// Describes a blueprint node to the functions visiting a blueprint
+type Node struct {
+	ID         string
+	Name       string
+	Details    string
+	Annotation string
+	// The dotted path of the group the node belongs to
+	Group  string
+	Module string
+	Args   []any
+	// The location of the script code declaring the action
+	Source string
+	// The directory relative paths of the arguments are resolved from
+	Dir string
+	// The position of the action in execution order, 0 for groups
+	Step      int
+	DependsOn []string
+	Notify    []string
+	When      *Condition
+	Phase     Phase
+	Retry     *RetryPolicy
+	OnError   ErrorPolicy
+	// Failures of the action are reported but don't fail the run
+	IgnoreErrors bool
+	Timeout      string
+	// The children of the node run concurrently
+	Parallel bool
+	// The number of ancestors of the node, set when walking a blueprint
+	Depth int
+	// The node runs a module, rather than grouping other nodes
+	Action  bool
+	Handler bool
+	// The node itself
+	Blueprint Blueprint
+}
+
+// Returned by a WalkFunc to skip the children of the visited node
+var SkipChildren = errors.New("skip children")
+
+// Visits a node of a blueprint, returning an error stops the walk
+type WalkFunc func(node Node) error
+
+// Walk visits every node of the blueprint in execution order, parents before their children and handlers last
+// The walk stops at the first error returned by fn, except for SkipChildren which only skips the children of the node
+//
+// Example:
+//
+//	blueprint.Walk(plan, func(node blueprint.Node) error {
+//		fmt.Println(strings.Repeat("  ", node.Depth), node.Name, node.Args)
+//		return nil
+//	})
+func Walk(blueprint Blueprint, fn WalkFunc) error {
+	return walk(blueprint, 0, fn)
+}
+
+// (internal)
+func walk(blueprint Blueprint, depth int, fn WalkFunc) error {
+	node := blueprint.Info()
+	node.Depth = depth
+
+	if err := fn(node); err != nil {
+		if errors.Is(err, SkipChildren) {
+			return nil
+		}
+		return err
+	}
+
+	for _, child := range blueprint.Nested() {
+		if err := walk(child, depth+1, fn); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Find returns the first node matching the predicate, in execution order
+func Find(blueprint Blueprint, match func(node Node) bool) (Node, bool) {
+	var found Node
+
+	errFound := errors.New("found")
+
+	err := Walk(blueprint, func(node Node) error {
+		if match(node) {
+			found = node
+			return errFound
+		}
+		return nil
+	})
+
+	return found, err == errFound
+}
+
+// Map replaces the nodes of the blueprint with the result of fn, children first
+// Returning node.Blueprint keeps the node as is, returning nil removes it (and its children) from the blueprint.
+// The returned blueprint is the replacement of the root, which is modified in place
+func Map(blueprint Blueprint, fn func(node Node) Blueprint) Blueprint {
+	return mapNode(blueprint, 0, fn)
+}
+
+// (internal)
+func mapNode(blueprint Blueprint, depth int, fn func(node Node) Blueprint) Blueprint {
+	nested := []Blueprint{}
+
+	for _, child := range blueprint.Nested() {
+		if mapped := mapNode(child, depth+1, fn); mapped != nil {
+			nested = append(nested, mapped)
+		}
+	}
+
+	blueprint.SetNested(nested)
+
+	node := blueprint.Info()
+	node.Depth = depth
+
+	return fn(node)
+}
+
+// Filter removes the nodes which don't match the predicate from the blueprint, along with their children
+// The root of the blueprint is always kept
+//
+// Example, removing every shell command from a plan:
+//
+//	blueprint.Filter(plan, func(node blueprint.Node) bool {
+//		return node.Module != "Sh"
+//	})
+func Filter(blueprint Blueprint, keep func(node Node) bool) {
+	Map(blueprint, func(node Node) Blueprint {
+		if node.Depth > 0 && !keep(node) {
+			return nil
+		}
+		return node.Blueprint
+	})
+}
+
+// Info returns the metadata of the node
+func (blueprint *SerialBlueprint) Info() Node {
+	return Node{
+		ID:           blueprint.ID,
+		Name:         blueprint.Name,
+		Details:      blueprint.Details,
+		Annotation:   blueprint.Annotation,
+		Group:        blueprint.Group,
+		Module:       blueprint.Module,
+		Args:         blueprint.Args,
+		Source:       blueprint.Source,
+		Dir:          blueprint.Dir,
+		Step:         blueprint.step,
+		DependsOn:    blueprint.DependsOn,
+		Notify:       blueprint.Notify,
+		When:         blueprint.When,
+		Phase:        blueprint.Phase,
+		Retry:        blueprint.Retry,
+		OnError:      blueprint.OnError,
+		IgnoreErrors: blueprint.IgnoreErrors,
+		Timeout:      blueprint.Timeout,
+		Action:       blueprint.isAction(),
+		Handler:      blueprint.Handler,
+		Blueprint:    blueprint,
+	}
+}
+
+// Nested returns the children of the node followed by its handlers
+func (blueprint *SerialBlueprint) Nested() []Blueprint {
+	nested := append([]Blueprint{}, blueprint.Children...)
+	return append(nested, blueprint.Handlers...)
+}
+
+// SetNested replaces the children and handlers of the node, handlers are identified by their metadata
+// Dependencies between the remaining children are preserved
+func (blueprint *SerialBlueprint) SetNested(nested []Blueprint) {
+	children := []Blueprint{}
+	handlers := []Blueprint{}
+
+	for _, bp := range nested {
+		if bp.Info().Handler {
+			handlers = append(handlers, bp)
+		} else {
+			children = append(children, bp)
+		}
+	}
+
+	blueprint.waits = remapWaits(blueprint.Children, blueprint.waits, children)
+	blueprint.Children = children
+	blueprint.Handlers = handlers
+}
+
+func (blueprint *ParallelBlueprint) Info() Node {
+	node := blueprint.SerialBlueprint.Info()
+	node.Blueprint = blueprint
+	node.Parallel = true
+	return node
+}
+
+// (internal)
+// Translates the indices of the children to wait for to a new list of children
+// Dependencies on removed children are dropped, as are the ones which would no longer come first
+func remapWaits(old []Blueprint, waits [][]int, children []Blueprint) [][]int {
+	if waits == nil {
+		return nil
+	}
+
+	position := map[Blueprint]int{}
+
+	for i, child := range children {
+		position[child] = i
+	}
+
+	remapped := make([][]int, len(children))
+
+	for i, deps := range waits {
+		if i >= len(old) {
+			break
+		}
+
+		pos, ok := position[old[i]]
+
+		if !ok {
+			continue
+		}
+
+		for _, dep := range deps {
+			if depPos, ok := position[old[dep]]; ok && depPos < pos {
+				remapped[pos] = append(remapped[pos], depPos)
+			}
+		}
+	}
+
+	return remapped
+}
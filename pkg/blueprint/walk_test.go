@@ -0,0 +1,91 @@
+package blueprint_test
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/patrixr/glue/pkg/blueprint"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWalk(t *testing.T) {
+	build := func() *SerialBlueprint {
+		plan := NewSerialBlueprint("root")
+		group := NewSerialBlueprint("configs")
+		group.Group = "configs"
+
+		group.Action(ActionDef{Name: "Copy", Group: "configs", Module: "Copy", Args: []any{"a"}, Fn: noop})
+		group.Action(ActionDef{Name: "Sh", Group: "configs", Module: "Sh", Args: []any{"echo"}, Fn: noop})
+		plan.Add(group)
+		plan.Action(ActionDef{Name: "Sh", Module: "Sh", Args: []any{"ls"}, Fn: noop})
+
+		handler := NewSerialBlueprint("reload")
+		handler.Action(ActionDef{Name: "Sh", Group: "reload", Module: "Sh", Args: []any{"reload"}, Fn: noop})
+		plan.AddHandler(handler)
+
+		return plan
+	}
+
+	t.Run("visits every node in execution order", func(t *testing.T) {
+		visited := []string{}
+
+		Walk(build(), func(node Node) error {
+			visited = append(visited, node.Name)
+			return nil
+		})
+
+		assert.Equal(t, []string{"root", "configs", "Copy", "Sh", "Sh", "reload", "Sh"}, visited)
+	})
+
+	t.Run("can skip the children of a node", func(t *testing.T) {
+		depths := []int{}
+
+		Walk(build(), func(node Node) error {
+			depths = append(depths, node.Depth)
+			if node.Name == "configs" || node.Handler {
+				return SkipChildren
+			}
+			return nil
+		})
+
+		assert.Equal(t, []int{0, 1, 1, 1}, depths)
+	})
+
+	t.Run("finds nodes from their metadata", func(t *testing.T) {
+		node, ok := Find(build(), func(node Node) bool {
+			return node.Module == "Sh" && node.Group == "configs"
+		})
+
+		assert.True(t, ok)
+		assert.Equal(t, []any{"echo"}, node.Args)
+
+		_, ok = Find(build(), func(node Node) bool { return node.Module == "Unknown" })
+		assert.False(t, ok)
+	})
+
+	t.Run("filters nodes out of the blueprint", func(t *testing.T) {
+		plan := build()
+
+		Filter(plan, func(node Node) bool {
+			return node.Module != "Sh"
+		})
+
+		assert.Equal(t, "+ root\n  + configs\n    + Copy\n  + reload (handler)\n", plan.PrettyPrint())
+		assert.Len(t, plan.Execute(context.Background()).Traces, 1)
+	})
+
+	t.Run("replaces nodes", func(t *testing.T) {
+		plan := Map(build(), func(node Node) Blueprint {
+			if node.Name == "configs" {
+				return NewParallelBlueprint("configs", 2)
+			}
+			return node.Blueprint
+		})
+
+		assert.Equal(t, "+ root\n  + configs (parallel, 2 jobs)\n  + Sh\n  + reload (handler)\n    + Sh\n", plan.PrettyPrint())
+	})
+}
+
+func noop(ctx context.Context, trace *Trace) error {
+	return nil
+}
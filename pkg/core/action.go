@@ -0,0 +1,194 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sync"
+
+	"github.com/patrixr/glue/pkg/runtime"
+)
+
+type actionScopeKey struct{}
+
+// ActionScope describes the environment a module action is executed in
+// Modules should write their output to the scope's writers rather than the global logger,
+// as actions of parallel groups run concurrently
+type ActionScope struct {
+	Group string
+	// The directory of the script declaring the action, see ScopedPath
+	Dir    string
+	Stdout io.Writer
+	Stderr io.Writer
+
+	changed bool
+	diff    bool
+	diffs   []string
+	undos   []func() error
+}
+
+// Changed marks the action as having changed the machine
+// When called from a check, it indicates that the action would change the machine
+func (scope *ActionScope) Changed() {
+	scope.changed = true
+}
+
+// WantsDiff indicates whether the action should record the diffs of the files it modifies
+func (scope *ActionScope) WantsDiff() bool {
+	return scope.diff
+}
+
+// Diff records the unified diff of a file modified by the action
+// Nothing is recorded when diffs are disabled or when the content is unchanged
+func (scope *ActionScope) Diff(path string, before string, after string) {
+	if !scope.diff || before == after {
+		return
+	}
+
+	scope.diffs = append(scope.diffs, UnifiedDiff(path, before, after))
+}
+
+// OnUndo registers a step reverting a change made by the action, used to roll back failed runs
+// Steps are replayed in the reverse order of their registration
+func (scope *ActionScope) OnUndo(fn func() error) {
+	scope.undos = append(scope.undos, fn)
+}
+
+// (internal)
+// Combines the undo steps of the action into a single function, nil if the action has none
+func (scope *ActionScope) undo() func() error {
+	if len(scope.undos) == 0 {
+		return nil
+	}
+
+	undos := scope.undos
+
+	return func() error {
+		for i := len(undos) - 1; i >= 0; i-- {
+			if err := undos[i](); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// WithActionScope returns a copy of the context carrying the action scope
+func WithActionScope(ctx context.Context, scope *ActionScope) context.Context {
+	return context.WithValue(ctx, actionScopeKey{}, scope)
+}
+
+// Scope returns the scope of the action currently being executed with the given arguments
+// Outside of an action, a default scope writing to the glue logger is returned
+func (glue *Glue) Scope(args *runtime.Arguments) *ActionScope {
+	if scope, ok := args.Context().Value(actionScopeKey{}).(*ActionScope); ok {
+		return scope
+	}
+
+	return glue.newActionScope("")
+}
+
+// (internal)
+// Returns a context to call module functions outside of the plan run, e.g. to validate an action declared in the directory
+func (glue *Glue) actionContext(dir string) context.Context {
+	scope := glue.newActionScope("")
+	scope.Dir = dir
+	return WithActionScope(glue.Context, scope)
+}
+
+// (internal)
+func (glue *Glue) newActionScope(group string) *ActionScope {
+	if !glue.Verbose {
+		return &ActionScope{
+			Group:  group,
+			Stdout: io.Discard,
+			Stderr: io.Discard,
+			diff:   glue.Diff,
+		}
+	}
+
+	return &ActionScope{
+		Group:  group,
+		Stdout: NewLabelWriter(group, glue.Log.Stdout),
+		Stderr: NewLabelWriter(group, glue.Log.Stderr),
+		diff:   glue.Diff,
+	}
+}
+
+// (internal)
+func (scope *ActionScope) flush() {
+	for _, w := range []io.Writer{scope.Stdout, scope.Stderr} {
+		if cw, ok := w.(*captureWriter); ok {
+			w = cw.Writer
+		}
+
+		if lw, ok := w.(*LabelWriter); ok {
+			lw.Flush()
+		}
+	}
+}
+
+// A writer which prefixes every line with a label
+// Lines are only written once complete, so that the output of concurrent actions doesn't get mixed up
+type LabelWriter struct {
+	label string
+	out   io.Writer
+	buf   []byte
+	mu    sync.Mutex
+}
+
+func NewLabelWriter(label string, out io.Writer) *LabelWriter {
+	return &LabelWriter{
+		label: label,
+		out:   out,
+	}
+}
+
+func (lw *LabelWriter) Write(p []byte) (int, error) {
+	lw.mu.Lock()
+	defer lw.mu.Unlock()
+
+	lw.buf = append(lw.buf, p...)
+
+	for {
+		idx := bytes.IndexByte(lw.buf, '\n')
+
+		if idx < 0 {
+			break
+		}
+
+		if err := lw.writeLine(lw.buf[:idx+1]); err != nil {
+			return 0, err
+		}
+
+		lw.buf = lw.buf[idx+1:]
+	}
+
+	return len(p), nil
+}
+
+// Flush writes any incomplete line left in the buffer
+func (lw *LabelWriter) Flush() error {
+	lw.mu.Lock()
+	defer lw.mu.Unlock()
+
+	if len(lw.buf) == 0 {
+		return nil
+	}
+
+	line := append(lw.buf, '\n')
+	lw.buf = nil
+
+	return lw.writeLine(line)
+}
+
+// (internal)
+func (lw *LabelWriter) writeLine(line []byte) error {
+	if len(lw.label) == 0 {
+		_, err := lw.out.Write(line)
+		return err
+	}
+
+	_, err := lw.out.Write(append([]byte("["+lw.label+"] "), line...))
+	return err
+}
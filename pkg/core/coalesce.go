@@ -0,0 +1,198 @@
+package core
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/patrixr/glue/pkg/blueprint"
+)
+
+// Merges the arguments of several calls of a module into the arguments of a single call
+// Each call holds the arguments of the module, without the action options
+type CoalesceFunc func(calls [][]any) ([]any, error)
+
+// Coalesce allows the calls of the module across the plan to be merged into a single action once the plan is compiled
+// The merged action runs at the position of the first call of its phase. Calls with action options, or within conditional groups
+// and handlers, are left as they are
+func (plug *plugin) Coalesce(fn CoalesceFunc) *plugin {
+	if plug.kind != MODULE {
+		panic("Only Glue modules can be coalesced")
+	}
+	plug.coalesce = fn
+	return plug
+}
+
+// (internal)
+// Merges the calls of the coalescing modules, the first call of each module receives the arguments of all of them
+// and records the items of each group. Groups left empty are removed. Returns whether any action was merged
+func (glue *Glue) coalesceActions(plan blueprint.Blueprint) (bool, error) {
+	calls := map[string][]*blueprint.SerialBlueprint{}
+	keys := []string{}
+	excluded := map[int]bool{}
+	phases := map[int]blueprint.Phase{-1: blueprint.PhaseMain}
+
+	blueprint.Walk(plan, func(node blueprint.Node) error {
+		// a node is excluded if it or one of its ancestors runs conditionally
+		excluded[node.Depth] = node.When != nil || node.Handler || (node.Depth > 0 && excluded[node.Depth-1])
+		phases[node.Depth] = node.Phase.Within(phases[node.Depth-1])
+
+		if !node.Action || excluded[node.Depth] {
+			return nil
+		}
+
+		mod := glue.findModule(node.Module)
+		action, ok := node.Blueprint.(*blueprint.SerialBlueprint)
+
+		if mod == nil || mod.coalesce == nil || !ok || !coalescable(action) {
+			return nil
+		}
+
+		// calls are only merged within their phase
+		key := mod.Name + "/" + string(phases[node.Depth])
+
+		if _, ok := calls[key]; !ok {
+			keys = append(keys, key)
+		}
+
+		calls[key] = append(calls[key], action)
+		return nil
+	})
+
+	merged := map[blueprint.Blueprint]bool{}
+
+	for _, key := range keys {
+		actions := calls[key]
+		name := actions[0].Module
+
+		if len(actions) < 2 {
+			continue
+		}
+
+		args := [][]any{}
+		items := []string{}
+		calls := []blueprint.MergedCall{}
+
+		for _, action := range actions {
+			call := blueprint.MergedCall{Group: groupName(action.Group), Summary: describeArgs(action.Args)}
+			args = append(args, action.Args)
+			items = append(items, fmt.Sprintf("%s (%s)", call.Group, call.Summary))
+			calls = append(calls, call)
+		}
+
+		combined, err := glue.findModule(name).coalesce(args)
+
+		if err != nil {
+			return false, fmt.Errorf("Unable to coalesce the calls of %s: %w", name, err)
+		}
+
+		leader := actions[0]
+		leader.Args = combined
+		leader.Details = fmt.Sprintf("Coalesced %d calls: %s", len(actions), strings.Join(items, ", "))
+		leader.Merged = calls
+
+		if err := leader.Bind(glue.BindAction); err != nil {
+			return false, err
+		}
+
+		for _, action := range actions[1:] {
+			merged[action] = true
+		}
+
+		glue.Log.Debug("[Coalesce]", "module", name, "calls", len(actions))
+	}
+
+	if len(merged) == 0 {
+		return false, nil
+	}
+
+	filled := map[blueprint.Blueprint]bool{}
+
+	blueprint.Walk(plan, func(node blueprint.Node) error {
+		filled[node.Blueprint] = len(node.Blueprint.Nested()) > 0
+		return nil
+	})
+
+	// children are filtered first, groups which only held merged calls end up empty
+	blueprint.Filter(plan, func(node blueprint.Node) bool {
+		emptied := !node.Action && filled[node.Blueprint] && len(node.Blueprint.Nested()) == 0
+		return !merged[node.Blueprint] && !emptied
+	})
+
+	return true, nil
+}
+
+// (internal)
+// Actions with options keep their own execution
+func coalescable(action *blueprint.SerialBlueprint) bool {
+	return action.When == nil &&
+		action.Retry == nil &&
+		len(action.Notify) == 0 &&
+		!action.IgnoreErrors &&
+		len(action.Timeout) == 0 &&
+		len(action.Register) == 0 &&
+		!resolvedLater(action.Args)
+}
+
+// (internal)
+func (glue *Glue) findModule(name string) *GluePlugin {
+	for _, mod := range glue.Modules {
+		if mod.Kind == MODULE && mod.Name == name {
+			return mod
+		}
+	}
+	return nil
+}
+
+// (internal)
+func groupName(group string) string {
+	if len(group) == 0 {
+		return RootLevel
+	}
+	return group
+}
+
+// (internal)
+// Describes the arguments of a call, e.g `packages: neovim, ripgrep`
+func describeArgs(args []any) string {
+	parts := []string{}
+
+	for _, arg := range args {
+		dict, ok := arg.(map[string]any)
+
+		if !ok {
+			parts = append(parts, fmt.Sprint(arg))
+			continue
+		}
+
+		keys := make([]string, 0, len(dict))
+
+		for key := range dict {
+			keys = append(keys, key)
+		}
+
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			value := dict[key]
+
+			if list, ok := value.([]any); ok {
+				if len(list) == 0 {
+					continue
+				}
+
+				values := make([]string, len(list))
+
+				for i, item := range list {
+					values[i] = fmt.Sprint(item)
+				}
+
+				value = strings.Join(values, ", ")
+			}
+
+			parts = append(parts, fmt.Sprintf("%s: %v", key, value))
+		}
+	}
+
+	return strings.Join(parts, "; ")
+}
@@ -0,0 +1,63 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// UnifiedDiff renders the changes between two versions of a file in the unified diff format
+func UnifiedDiff(path string, before string, after string) string {
+	if isBinary(before) || isBinary(after) {
+		return fmt.Sprintf("Binary file %s differs\n", path)
+	}
+
+	from := "a/" + strings.TrimPrefix(path, "/")
+	to := "b/" + strings.TrimPrefix(path, "/")
+
+	if len(before) == 0 {
+		from = "/dev/null"
+	}
+
+	if len(after) == 0 {
+		to = "/dev/null"
+	}
+
+	diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        splitLines(before),
+		B:        splitLines(after),
+		FromFile: from,
+		ToFile:   to,
+		Context:  3,
+	})
+
+	if err != nil {
+		return fmt.Sprintf("Unable to compute the diff of %s: %s\n", path, err)
+	}
+
+	return diff
+}
+
+// (internal)
+// Splits the content into lines, each of them terminated by a newline
+func splitLines(content string) []string {
+	if len(content) == 0 {
+		return nil
+	}
+
+	lines := strings.SplitAfter(content, "\n")
+
+	if last := len(lines) - 1; lines[last] == "" {
+		lines = lines[:last]
+	} else {
+		lines[last] += "\n"
+	}
+
+	return lines
+}
+
+// (internal)
+func isBinary(content string) bool {
+	return strings.IndexByte(content, 0) >= 0
+}
@@ -0,0 +1,20 @@
+package core_test
+
+import (
+	"testing"
+
+	. "github.com/patrixr/glue/pkg/core"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	diff := UnifiedDiff("/home/user/.zshrc", "one\ntwo\nthree\n", "one\n2\nthree\n")
+
+	assert.Equal(t, "--- a/home/user/.zshrc\n+++ b/home/user/.zshrc\n@@ -1,3 +1,3 @@\n one\n-two\n+2\n three\n", diff)
+
+	diff = UnifiedDiff("/tmp/new", "", "content")
+
+	assert.Equal(t, "--- /dev/null\n+++ b/tmp/new\n@@ -0,0 +1 @@\n+content\n", diff)
+
+	assert.Equal(t, "Binary file /tmp/bin differs\n", UnifiedDiff("/tmp/bin", "a\x00", "b\x00"))
+}
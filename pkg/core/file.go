@@ -91,3 +91,24 @@ func GlueHome() (string, error) {
 	configFolder := filepath.Join(homedir, ".config")
 	return filepath.Join(configFolder, "glue"), nil
 }
+
+// @auteur("Configuration")
+//
+// # XDG_STATE_HOME
+//
+// Glue keeps the outcome of its runs, used by `--resume` and `--rerun-failed`, in a state directory.
+// It is located under `XDG_STATE_HOME` when the variable is set, and defaults to `~/.local/state/glue` otherwise.
+func GlueStateDir() (string, error) {
+	xdgStateHome := os.Getenv("XDG_STATE_HOME")
+	if xdgStateHome != "" {
+		return filepath.Join(xdgStateHome, "glue"), nil
+	}
+
+	homedir, err := os.UserHomeDir()
+
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(homedir, ".local", "state", "glue"), nil
+}
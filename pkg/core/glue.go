@@ -6,6 +6,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	. "github.com/patrixr/glue/pkg/blueprint"
@@ -19,24 +20,51 @@ type Glue struct {
 	q.Eventful
 	Testable
 
-	Stack        GlueStack
-	BluePrint    Blueprint
-	Verbose      bool
-	Done         bool
-	Unsafe       bool
-	FailFast     bool
-	Log          *GlueLogger
-	Modules      []*GluePlugin
-	UserSelector Selector
-	Cache        q.Cache[string]
-	Context      context.Context
-	Runtime      runtime.Runtime
-	Machine      machine.Machine
+	Stack     GlueStack
+	BluePrint Blueprint
+	Verbose   bool
+	Done      bool
+	Unsafe    bool
+	FailFast  bool
+	// Resources managed by several groups fail the plan, see checkOwnership
+	StrictOwnership bool
+	Jobs            int
+	NoDeps          bool
+	Diff            bool
+	Log             *GlueLogger
+	Modules         []*GluePlugin
+	UserSelector    Selector
+	Cache           q.Cache[string]
+	Context         context.Context
+	Runtime         runtime.Runtime
+	Machine         machine.Machine
+
+	pending  []*pendingGroup
+	compiled map[string][]string
+	required [][]string
+	root     Blueprint
+	handlers map[string]Blueprint
+	notified []string
+	// the results of the registered actions, see ActionOpts.Register
+	registered registry
+	// the functions of the script passed to later()
+	deferred deferredValues
+	// serializes the calls of script functions once the plan runs, see evaluate
+	evaluating sync.Mutex
+	// the resources managed by the actions of the plan, see checkOwnership
+	owners    []Owner
+	conflicts []Conflict
 }
 
 type GlueOptions struct {
 	Selector string
 	Verbose  bool
+	Jobs     int
+	NoDeps   bool
+	Diff     bool
+	FailFast bool
+	// Fail plans in which several groups manage the same resources
+	StrictOwnership bool
 }
 
 func NewGlue() *Glue {
@@ -49,16 +77,23 @@ func NewGlueWithOptions(options GlueOptions) *Glue {
 	ctx := context.Background()
 
 	glue := &Glue{
-		Runtime:      lua.NewLuaRuntime(),
-		Eventful:     q.NewEventEmitter(ctx, 1),
-		Testable:     NewTestSuite(),
-		Verbose:      options.Verbose,
-		UserSelector: NewSelectorWithPrefix(options.Selector, []string{RootLevel}),
-		Log:          logger,
-		Cache:        q.NewInMemoryCache[string](time.Hour * 8760),
-		Context:      ctx,
-		BluePrint:    nil,
-		Machine:      machine.NewLocalMachine(),
+		Runtime:         lua.NewLuaRuntime(),
+		Eventful:        q.NewEventEmitter(ctx, 1),
+		Testable:        NewTestSuite(),
+		Verbose:         options.Verbose,
+		Jobs:            options.Jobs,
+		NoDeps:          options.NoDeps,
+		Diff:            options.Diff,
+		FailFast:        options.FailFast,
+		StrictOwnership: options.StrictOwnership,
+		UserSelector:    NewSelectorWithPrefix(options.Selector, []string{RootLevel}),
+		Log:             logger,
+		Cache:           q.NewInMemoryCache[string](time.Hour * 8760),
+		Context:         ctx,
+		BluePrint:       nil,
+		Machine:         machine.NewLocalMachine(),
+		compiled:        map[string][]string{},
+		handlers:        map[string]Blueprint{},
 	}
 
 	InstallNativeGlueModules(glue)
@@ -73,10 +108,15 @@ func (glue *Glue) CompilePlan(file string) (Blueprint, error) {
 		return nil, errors.New("Unable to reuse the same Glue instance")
 	}
 
+	// the root runs in declaration order, only groups marked as parallel run concurrently
 	glue.BluePrint = NewSerialBlueprint("<root>")
 
+	glue.root = glue.BluePrint
+	glue.applyFailFast(glue.BluePrint)
+
 	defer func() {
 		glue.BluePrint = nil
+		glue.root = nil
 	}()
 
 	_, errors := glue.Fire(EV_GLUE_PLAN_END, glue)
@@ -89,6 +129,48 @@ func (glue *Glue) CompilePlan(file string) (Blueprint, error) {
 		return nil, err
 	}
 
+	if !glue.NoDeps {
+		if err := glue.compileDependencies(); err != nil {
+			return nil, err
+		}
+	}
+
+	phased, err := SplitPhases(glue.BluePrint)
+
+	if err != nil {
+		return nil, err
+	}
+
+	glue.BluePrint = phased
+
+	if err := Resolve(glue.BluePrint, glue.NoDeps); err != nil {
+		return nil, err
+	}
+
+	if err := glue.validatePlan(glue.BluePrint); err != nil {
+		return nil, err
+	}
+
+	// before coalescing, which merges the calls of several groups
+	if err := glue.checkOwnership(glue.BluePrint); err != nil {
+		return nil, err
+	}
+
+	merged, err := glue.coalesceActions(glue.BluePrint)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// the remaining actions are numbered again, groups emptied by coalescing may be depended on
+	if merged {
+		if err := Resolve(glue.BluePrint, true); err != nil {
+			return nil, err
+		}
+	}
+
+	glue.warnUnknownHandlers()
+
 	_, errors = glue.Fire(EV_GLUE_PLAN_END, glue)
 
 	if len(errors) > 0 {
@@ -98,6 +180,78 @@ func (glue *Glue) CompilePlan(file string) (Blueprint, error) {
 	return glue.BluePrint, nil
 }
 
+// LoadPlan reads a blueprint bundle previously written with blueprint.Save
+// The actions are bound to the modules installed on this instance, no script is executed
+func (glue *Glue) LoadPlan(file string) (Blueprint, error) {
+	if glue.Done {
+		return nil, errors.New("Unable to reuse the same Glue instance")
+	}
+
+	path, err := glue.SmartPath(file)
+
+	if err != nil {
+		return nil, err
+	}
+
+	reader, err := os.Open(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer reader.Close()
+
+	plan, err := Load(reader, glue.BindAction)
+
+	if err != nil {
+		return nil, err
+	}
+
+	glue.applyJobs(plan)
+
+	if err := glue.validatePlan(plan); err != nil {
+		return nil, err
+	}
+
+	if err := glue.checkOwnership(plan); err != nil {
+		return nil, err
+	}
+
+	glue.applyFailFast(plan)
+
+	return plan, nil
+}
+
+// (internal)
+// Limits the parallel groups of a loaded plan to the jobs of the options, rather than the ones it was saved with
+func (glue *Glue) applyJobs(plan Blueprint) {
+	if glue.Jobs <= 0 {
+		return
+	}
+
+	Walk(plan, func(node Node) error {
+		if parallel, ok := node.Blueprint.(*ParallelBlueprint); ok {
+			parallel.Jobs = glue.Jobs
+		}
+		return nil
+	})
+}
+
+// (internal)
+// Stops the whole plan after the first failure
+func (glue *Glue) applyFailFast(plan Blueprint) {
+	if !glue.FailFast {
+		return
+	}
+
+	switch root := plan.(type) {
+	case *SerialBlueprint:
+		root.OnError = OnErrorStop
+	case *ParallelBlueprint:
+		root.OnError = OnErrorStop
+	}
+}
+
 // (internal)
 // Checks if the current group is allowed to run based on the user's filter options
 func (glue *Glue) canRunGroup(group string) (bool, error) {
@@ -114,7 +268,33 @@ func (glue *Glue) canRunGroup(group string) (bool, error) {
 		return grp.Name
 	})
 
-	return glue.UserSelector.Test(append(groups, group))
+	allowed, err := glue.UserSelector.Test(append(groups, group))
+
+	if allowed || err != nil {
+		return allowed, err
+	}
+
+	// Groups required by selected groups are allowed to run as well
+	return glue.isRequired(append(glue.Stack.GroupPath(), group)), nil
+}
+
+// (internal)
+// Checks if the current group contains selected or required groups, in which case it has to be traversed
+func (glue *Glue) leadsToGroup(group string) bool {
+	if len(glue.Stack.ExecutionStack) == 0 {
+		return false
+	}
+
+	script := glue.Stack.ActiveScript()
+	groups := q.Map(script.GroupStack, func(grp *GlueCodeGroup) string {
+		return grp.Name
+	})
+
+	if glue.UserSelector.Partial(append(groups, group)) {
+		return true
+	}
+
+	return glue.leadsToRequired(append(glue.Stack.GroupPath(), group))
 }
 
 // (internal)
@@ -164,6 +344,42 @@ func (glue *Glue) Getwd() (string, error) {
 // SmartPath resolves a path to an absolute path
 // If called from within a script, it resolves the path relative to the script's directory
 func (glue *Glue) SmartPath(path string) (string, error) {
+	return resolvePath(path, glue.Getwd)
+}
+
+// ScopedPath resolves a path of the arguments of a module relatively to the script declaring the action
+// Actions run once the script is done, and concurrently in parallel groups, the script stack no longer applies to them
+func (glue *Glue) ScopedPath(args *runtime.Arguments, path string) (string, error) {
+	scope := glue.Scope(args)
+
+	if len(scope.Dir) == 0 {
+		return glue.SmartPath(path)
+	}
+
+	return resolvePath(path, func() (string, error) {
+		return scope.Dir, nil
+	})
+}
+
+// (internal)
+// Returns the absolute directory of the active script, as relative paths of its actions are resolved from it
+func (glue *Glue) scriptDir() string {
+	wd, err := glue.Getwd()
+
+	if err != nil {
+		return ""
+	}
+
+	if abs, err := filepath.Abs(wd); err == nil {
+		return abs
+	}
+
+	return wd
+}
+
+// (internal)
+// Expands the home directory, and joins relative paths to the working directory
+func resolvePath(path string, getwd func() (string, error)) (string, error) {
 	homedir, err := os.UserHomeDir()
 	if err != nil {
 		return "", err
@@ -181,7 +397,7 @@ func (glue *Glue) SmartPath(path string) (string, error) {
 		return path, nil
 	}
 
-	wd, err := glue.Getwd()
+	wd, err := getwd()
 	if err != nil {
 		return "", err
 	}
@@ -1,8 +1,16 @@
 package core
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 
+	"github.com/patrixr/glue/pkg/blueprint"
 	"github.com/patrixr/glue/pkg/runtime"
 	"github.com/stretchr/testify/assert"
 )
@@ -38,3 +46,586 @@ func Test_FunctionCalling(t *testing.T) {
 		})
 	})
 }
+
+func Test_ModuleActions(t *testing.T) {
+	received := map[interface{}]interface{}{}
+	glue := NewGlue()
+
+	defer glue.Close()
+
+	glue.Plug("foo", MODULE).
+		Arg("opts", runtime.DICT, "options").
+		Do(func(R runtime.Runtime, args *runtime.Arguments) (runtime.RTValue, error) {
+			received = args.EnsureDict(0).Map()
+			return nil, nil
+		})
+
+	plan := blueprint.NewSerialBlueprint("<root>")
+	glue.BluePrint = plan
+
+	err := glue.execString(`Foo({ name = "bar", items = { "a", "b" } })`)
+
+	assert.NoError(t, err)
+
+	t.Run("should store the module arguments as data", func(t *testing.T) {
+		action := plan.Children[0].(*blueprint.SerialBlueprint)
+		assert.Equal(t, "Foo", action.Module)
+		assert.Equal(t, []any{map[string]any{"name": "bar", "items": []any{"a", "b"}}}, action.Args)
+	})
+
+	t.Run("should rebuild the action from its data", func(t *testing.T) {
+		action := plan.Children[0].(*blueprint.SerialBlueprint)
+		bound, err := glue.BindAction(blueprint.ActionDef{Module: action.Module, Args: action.Args})
+
+		assert.NoError(t, err)
+		assert.NoError(t, bound.Fn(context.Background(), &blueprint.Trace{}))
+		assert.Equal(t, "bar", received["Name"])
+		assert.Equal(t, []interface{}{"a", "b"}, received["Items"])
+	})
+
+	t.Run("should fail to bind an unknown module", func(t *testing.T) {
+		_, err := glue.BindAction(blueprint.ActionDef{Module: "Unknown"})
+		assert.Error(t, err)
+	})
+}
+
+func Test_GroupDependencies(t *testing.T) {
+	script := filepath.Join(t.TempDir(), "glue.lua")
+
+	os.WriteFile(script, []byte(`
+		group("configs", function()
+			group("nvim", { depends_on = { "homebrew" } }, function()
+				Foo()
+			end)
+			group("zsh", function()
+				Foo()
+			end)
+		end)
+		group("homebrew", function()
+			Foo()
+		end)
+	`), 0644)
+
+	compile := func(options GlueOptions) *blueprint.SerialBlueprint {
+		glue := NewGlueWithOptions(options)
+		defer glue.Close()
+
+		glue.Plug("foo", MODULE).
+			Do(func(R runtime.Runtime, args *runtime.Arguments) (runtime.RTValue, error) {
+				return nil, nil
+			})
+
+		plan, err := glue.CompilePlan(script)
+		assert.NoError(t, err)
+
+		return plan.(*blueprint.SerialBlueprint)
+	}
+
+	t.Run("should run dependencies first", func(t *testing.T) {
+		plan := compile(GlueOptions{})
+		assert.Equal(t, "+ <root>\n  + homebrew\n    + Foo [homebrew/foo-1-4f53cd]\n  + configs\n    + nvim (after homebrew)\n      + Foo [configs.nvim/foo-1-4f53cd]\n    + zsh\n      + Foo [configs.zsh/foo-1-4f53cd]\n", plan.PrettyPrint())
+	})
+
+	t.Run("should include the dependencies of selected groups", func(t *testing.T) {
+		plan := compile(GlueOptions{Selector: "configs.nvim"})
+		assert.Equal(t, "+ <root>\n  + homebrew\n    + Foo [homebrew/foo-1-4f53cd]\n  + configs\n    + nvim (after homebrew)\n      + Foo [configs.nvim/foo-1-4f53cd]\n", plan.PrettyPrint())
+	})
+
+	t.Run("should not include dependencies when disabled", func(t *testing.T) {
+		plan := compile(GlueOptions{Selector: "configs.nvim", NoDeps: true})
+		assert.Equal(t, "+ <root>\n  + configs\n    + nvim (after homebrew)\n      + Foo [configs.nvim/foo-1-4f53cd]\n", plan.PrettyPrint())
+	})
+
+	t.Run("should keep the root serial with several jobs", func(t *testing.T) {
+		plan := compile(GlueOptions{Jobs: 4})
+		assert.Equal(t, "homebrew", plan.Children[0].Info().Name)
+	})
+}
+
+func Test_RetryOptions(t *testing.T) {
+	glue := NewGlue()
+
+	defer glue.Close()
+
+	glue.Plug("foo", MODULE).
+		Arg("name", runtime.STRING, "name").
+		Do(func(R runtime.Runtime, args *runtime.Arguments) (runtime.RTValue, error) {
+			return nil, nil
+		})
+
+	plan := blueprint.NewSerialBlueprint("<root>")
+	glue.BluePrint = plan
+
+	err := glue.execString(`
+		Foo("a", { retry = { attempts = 3, delay = "1s", backoff = 2 } })
+		group("flaky", { retry = { attempts = 2 } }, function()
+			Foo("b")
+			Foo("c", { retry = { attempts = 5 } })
+		end)
+		Foo("d")
+	`)
+
+	assert.NoError(t, err)
+
+	action := func(path ...int) *blueprint.SerialBlueprint {
+		node := plan
+		for _, i := range path {
+			node = node.Children[i].(*blueprint.SerialBlueprint)
+		}
+		return node
+	}
+
+	assert.Equal(t, &blueprint.RetryPolicy{Attempts: 3, Delay: "1s", Backoff: 2}, action(0).Retry)
+	assert.Equal(t, []any{"a"}, action(0).Args, "options are not part of the module arguments")
+	assert.Equal(t, 2, action(1, 0).Retry.Attempts, "actions inherit the policy of their group")
+	assert.Equal(t, 5, action(1, 1).Retry.Attempts, "actions can override the policy of their group")
+	assert.Nil(t, action(2).Retry)
+
+	assert.Error(t, glue.execString(`Foo("e", { retry = { attempts = 2, delay = "soon" } })`))
+}
+
+func Test_Handlers(t *testing.T) {
+	script := filepath.Join(t.TempDir(), "glue.lua")
+
+	os.WriteFile(script, []byte(`
+		group("configs", function()
+			Foo({ name = "tmux", notify = "reload" })
+			Foo({ name = "zsh" }, { notify = { "reload", "restart" } })
+		end)
+		handler("reload", function()
+			Foo({ name = "reload" })
+		end)
+	`), 0644)
+
+	glue := NewGlue()
+	defer glue.Close()
+
+	glue.Plug("foo", MODULE).
+		Arg("opts", runtime.DICT, "options").
+		Do(func(R runtime.Runtime, args *runtime.Arguments) (runtime.RTValue, error) {
+			return nil, nil
+		})
+
+	plan, err := glue.CompilePlan(script)
+	assert.NoError(t, err)
+
+	root := plan.(*blueprint.SerialBlueprint)
+	group := root.Children[0].(*blueprint.SerialBlueprint)
+
+	t.Run("should read notifications inlined in the module arguments", func(t *testing.T) {
+		action := group.Children[0].(*blueprint.SerialBlueprint)
+		assert.Equal(t, []string{"reload"}, action.Notify)
+		assert.Equal(t, []any{map[string]any{"name": "tmux"}}, action.Args)
+	})
+
+	t.Run("should read notifications from the options", func(t *testing.T) {
+		action := group.Children[1].(*blueprint.SerialBlueprint)
+		assert.Equal(t, []string{"reload", "restart"}, action.Notify)
+	})
+
+	t.Run("should compile handlers at the end of the plan", func(t *testing.T) {
+		assert.Equal(t, "+ <root>\n  + configs\n    + Foo [configs/foo-1-5b1c39]\n    + Foo [configs/foo-2-ea2caa]\n  + reload (handler)\n    + Foo [reload/foo-1-25f0df]\n", plan.PrettyPrint())
+	})
+
+	t.Run("should reject duplicate handlers", func(t *testing.T) {
+		err := glue.execString(`handler("reload", function() end)`)
+		assert.Error(t, err)
+	})
+}
+
+func Test_Conditions(t *testing.T) {
+	glue := NewGlue()
+
+	defer glue.Close()
+
+	ran := []string{}
+
+	glue.Plug("foo", MODULE).
+		Arg("name", runtime.STRING, "name").
+		Do(func(R runtime.Runtime, args *runtime.Arguments) (runtime.RTValue, error) {
+			ran = append(ran, args.EnsureString(0).String())
+			return nil, nil
+		})
+
+	plan := blueprint.NewSerialBlueprint("<root>")
+	glue.BluePrint = plan
+
+	err := glue.execString(`
+		enabled = false
+		Foo("shell", { when = "true" })
+		Foo("function", { when = function() return enabled end })
+		group("skipped", { when = "false" }, function()
+			Foo("grouped")
+		end)
+		Foo("expanded", { when = "test -n \"$HOME\" && [ 'a b' = 'a b' ]" })
+		Foo("piped", { when = "echo glue | grep -q nothing || false" })
+		enabled = true
+	`)
+
+	assert.NoError(t, err)
+
+	results := plan.Execute(context.Background())
+
+	assert.True(t, results.Success)
+	assert.Equal(t, []string{"shell", "function", "expanded"}, ran, "conditions are evaluated by a shell when the plan runs")
+	assert.Equal(t, blueprint.StatusSkipped, results.Traces[2].Status)
+}
+
+func Test_ErrorOptions(t *testing.T) {
+	glue := NewGlue()
+
+	defer glue.Close()
+
+	glue.Plug("foo", MODULE).
+		Arg("name", runtime.STRING, "name").
+		Do(func(R runtime.Runtime, args *runtime.Arguments) (runtime.RTValue, error) {
+			return nil, nil
+		})
+
+	plan := blueprint.NewSerialBlueprint("<root>")
+	glue.BluePrint = plan
+
+	err := glue.execString(`
+		group("macos", { on_error = "stop-group" }, function()
+			Foo("a", { ignore_errors = true, timeout = "10m" })
+		end)
+	`)
+
+	assert.NoError(t, err)
+
+	group := plan.Children[0].(*blueprint.SerialBlueprint)
+	action := group.Children[0].(*blueprint.SerialBlueprint)
+
+	assert.Equal(t, blueprint.OnErrorStopGroup, group.OnError)
+	assert.True(t, action.IgnoreErrors)
+	assert.Equal(t, "10m", action.Timeout)
+	assert.Error(t, glue.execString(`Foo("b", { timeout = "soon" })`))
+	assert.Error(t, glue.execString(`group("invalid", { on_error = "panic" }, function() end)`))
+}
+
+func Test_Coalescing(t *testing.T) {
+	script := filepath.Join(t.TempDir(), "glue.lua")
+
+	os.WriteFile(script, []byte(`
+		group("homebrew", function()
+			Foo("git")
+		end)
+		group("configs", function()
+			Foo("neovim")
+			Foo("conditional", { when = "false" })
+		end)
+		group("dotfiles", function()
+			Foo("stow")
+		end)
+		Foo("root")
+	`), 0644)
+
+	glue := NewGlue()
+	defer glue.Close()
+
+	received := []string{}
+
+	glue.Plug("foo", MODULE).
+		Arg("name", runtime.STRING, "name").
+		Coalesce(func(calls [][]any) ([]any, error) {
+			names := []string{}
+			for _, call := range calls {
+				names = append(names, call[0].(string))
+			}
+			return []any{strings.Join(names, ",")}, nil
+		}).
+		Do(func(R runtime.Runtime, args *runtime.Arguments) (runtime.RTValue, error) {
+			received = append(received, args.EnsureString(0).String())
+			return nil, nil
+		})
+
+	plan, err := glue.CompilePlan(script)
+	assert.NoError(t, err)
+
+	leader := plan.(*blueprint.SerialBlueprint).Children[0].(*blueprint.SerialBlueprint).Children[0].(*blueprint.SerialBlueprint)
+
+	t.Run("should merge the calls into the first one and remove the emptied groups", func(t *testing.T) {
+		assert.Equal(t, "+ <root>\n  + homebrew\n    + Foo [homebrew/foo-1-dcf095]\n  + configs\n    + Foo [configs/foo-1-199d99] (when `false`)\n", plan.PrettyPrint())
+	})
+
+	t.Run("should attribute the calls to their groups", func(t *testing.T) {
+		assert.Equal(t, "Coalesced 4 calls: homebrew (git), configs (neovim), dotfiles (stow), root (root)", leader.Details)
+		assert.Equal(t, []blueprint.MergedCall{
+			{Group: "homebrew", Summary: "git"},
+			{Group: "configs", Summary: "neovim"},
+			{Group: "dotfiles", Summary: "stow"},
+			{Group: "root", Summary: "root"},
+		}, leader.Merged)
+	})
+
+	t.Run("should run the merged action once", func(t *testing.T) {
+		results := plan.Execute(context.Background())
+		assert.Equal(t, []string{"git,neovim,stow,root"}, received)
+		assert.Equal(t, leader.Merged, results.Traces[0].Merged, "the trace attributes the calls to their groups")
+	})
+}
+
+func Test_RegisteredResults(t *testing.T) {
+	glue := NewGlue()
+
+	defer glue.Close()
+
+	received := []any{}
+
+	glue.Plug("produce", MODULE).
+		Arg("text", runtime.STRING, "text").
+		Do(func(R runtime.Runtime, args *runtime.Arguments) (runtime.RTValue, error) {
+			scope := glue.Scope(args)
+			scope.Changed()
+			fmt.Fprintln(scope.Stdout, args.EnsureString(0).String())
+			return nil, nil
+		})
+
+	glue.Plug("consume", MODULE).
+		Arg("opts", runtime.DICT, "opts").
+		Do(func(R runtime.Runtime, args *runtime.Arguments) (runtime.RTValue, error) {
+			opts, _ := R.ToNative(args.Get(0))
+			received = append(received, opts)
+			return nil, nil
+		})
+
+	plan := blueprint.NewSerialBlueprint("<root>")
+	glue.BluePrint = plan
+
+	err := glue.execString(`
+		local r = Produce("hello", { register = true })
+		Consume({ text = r.stdout, rc = r.rc }, { when = r.changed })
+		Consume({ text = "unchanged" }, { when = r.stdout })
+	`)
+
+	assert.NoError(t, err)
+
+	results := plan.Execute(context.Background())
+
+	assert.True(t, results.Success)
+	assert.Equal(t, []any{
+		map[string]any{"text": "hello", "rc": float64(0)},
+		map[string]any{"text": "unchanged"},
+	}, received)
+
+	t.Run("fails actions using a result which is not available", func(t *testing.T) {
+		plan := blueprint.NewSerialBlueprint("<root>")
+		glue.BluePrint = plan
+
+		err := glue.execString(`
+			local r = Produce("late", { register = true, phase = "post" })
+			Consume({ text = r.stdout })
+		`)
+
+		assert.NoError(t, err)
+
+		results := plan.Children[1].Execute(context.Background())
+		assert.ErrorContains(t, results.Traces[0].Error, "not available")
+	})
+}
+
+func Test_DeferredValues(t *testing.T) {
+	glue := NewGlue()
+
+	defer glue.Close()
+
+	received := []any{}
+
+	glue.Plug("consume", MODULE).
+		Arg("opts", runtime.DICT, "opts").
+		Do(func(R runtime.Runtime, args *runtime.Arguments) (runtime.RTValue, error) {
+			opts, _ := R.ToNative(args.Get(0))
+			received = append(received, opts)
+			return nil, nil
+		})
+
+	plan := blueprint.NewSerialBlueprint("<root>")
+	glue.BluePrint = plan
+
+	err := glue.execString(`
+		value = "compiled"
+		Consume({ text = later(function() return value end) })
+		value = "executed"
+	`)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []any{map[string]any{"text": blueprint.Pending{Name: "later-1"}.Native()}}, plan.Children[0].(*blueprint.SerialBlueprint).Args)
+	assert.Error(t, blueprint.Save(plan, "glue.lua", &strings.Builder{}), "deferred values only exist within the script")
+
+	results := plan.Execute(context.Background())
+
+	assert.True(t, results.Success)
+	assert.Equal(t, []any{map[string]any{"text": "executed"}}, received)
+}
+
+func Test_DeferredValuesInParallelGroups(t *testing.T) {
+	script := filepath.Join(t.TempDir(), "glue.lua")
+
+	os.WriteFile(script, []byte(`
+		group("parallel", { parallel = true }, function()
+			for i = 1, 8 do
+				-- long enough for the actions to overlap
+				local value = later(function()
+					for _ = 1, 100000 do end
+					return "value-" .. i
+				end)
+				Consume({ text = value, when = function() return true end })
+			end
+		end)
+	`), 0644)
+
+	glue := NewGlueWithOptions(GlueOptions{Jobs: 8})
+	defer glue.Close()
+
+	lock := sync.Mutex{}
+	received := []string{}
+
+	glue.Plug("consume", MODULE).
+		Arg("opts", runtime.DICT, "opts").
+		Do(func(R runtime.Runtime, args *runtime.Arguments) (runtime.RTValue, error) {
+			lock.Lock()
+			defer lock.Unlock()
+			received = append(received, fmt.Sprint(args.EnsureDict(0).Map()["Text"]))
+			return nil, nil
+		})
+
+	plan, err := glue.CompilePlan(script)
+	assert.NoError(t, err)
+
+	// the functions are evaluated within their script while the actions run concurrently, see `go test -race`
+	results := plan.Execute(context.Background())
+
+	assert.True(t, results.Success)
+	assert.ElementsMatch(t, []string{"value-1", "value-2", "value-3", "value-4", "value-5", "value-6", "value-7", "value-8"}, received)
+	assert.Empty(t, glue.Stack.ExecutionStack, "the script stack is restored")
+}
+
+func Test_Validation(t *testing.T) {
+	script := filepath.Join(t.TempDir(), "glue.lua")
+
+	os.WriteFile(script, []byte(`
+		Foo("valid")
+		group("configs", function()
+			Foo("")
+		end)
+		Foo("")
+	`), 0644)
+
+	glue := NewGlue()
+
+	defer glue.Close()
+
+	ran := false
+
+	glue.Plug("foo", MODULE).
+		Arg("name", runtime.STRING, "name").
+		Validate(func(R runtime.Runtime, args *runtime.Arguments) error {
+			if len(args.EnsureString(0).String()) == 0 {
+				return errors.New("Missing name")
+			}
+			return nil
+		}).
+		Do(func(R runtime.Runtime, args *runtime.Arguments) (runtime.RTValue, error) {
+			ran = true
+			return nil, nil
+		})
+
+	_, err := glue.CompilePlan(script)
+
+	assert.False(t, ran)
+	assert.ErrorContains(t, err, "Found 2 invalid actions")
+	assert.ErrorContains(t, err, "configs ("+script+":4) Foo: Missing name")
+	assert.ErrorContains(t, err, "root ("+script+":6) Foo: Missing name")
+}
+
+func Test_ValidationOfPendingValues(t *testing.T) {
+	script := filepath.Join(t.TempDir(), "glue.lua")
+
+	os.WriteFile(script, []byte(`
+		Foo({ source = later(function() return "a" end), dest = "b", mode = later(function() return "copy" end) })
+		Foo({ source = later(function() return "a" end) })
+		Foo(later(function() return {} end))
+	`), 0644)
+
+	glue := NewGlue()
+
+	defer glue.Close()
+
+	glue.Plug("foo", MODULE).
+		Arg("opts", runtime.DICT, "options").
+		Validate(func(R runtime.Runtime, args *runtime.Arguments) error {
+			opts := args.EnsureDict(0).Map()
+
+			if opts["Dest"] == nil {
+				return errors.New("Missing dest")
+			}
+
+			if mode, ok := opts["Mode"].(string); ok && mode != "copy" {
+				return fmt.Errorf("Invalid mode %s", mode)
+			}
+
+			return nil
+		}).
+		Do(func(R runtime.Runtime, args *runtime.Arguments) (runtime.RTValue, error) {
+			return nil, nil
+		})
+
+	_, err := glue.CompilePlan(script)
+
+	// the known fields are validated, errors about the pending ones are left to the run
+	assert.ErrorContains(t, err, "Found 1 invalid actions")
+	assert.ErrorContains(t, err, "root ("+script+":3) Foo: Missing dest")
+}
+
+func Test_Ownership(t *testing.T) {
+	script := filepath.Join(t.TempDir(), "glue.lua")
+
+	os.WriteFile(script, []byte(`
+		group("shell", function()
+			Write("/home/.zshrc")
+			group("plugins", function()
+				Write("/home/.zshrc")
+			end)
+		end)
+		group("tools", function()
+			Write("/home")
+		end)
+	`), 0644)
+
+	compile := func(strict bool) (*Glue, error) {
+		glue := NewGlueWithOptions(GlueOptions{StrictOwnership: strict})
+
+		glue.Plug("write", MODULE).
+			Arg("path", runtime.STRING, "path").
+			Resources(func(R runtime.Runtime, args *runtime.Arguments) ([]blueprint.Resource, error) {
+				return []blueprint.Resource{{Kind: blueprint.ResourcePath, Name: args.EnsureString(0).String()}}, nil
+			}).
+			Do(func(R runtime.Runtime, args *runtime.Arguments) (runtime.RTValue, error) {
+				return nil, nil
+			})
+
+		_, err := glue.CompilePlan(script)
+		return glue, err
+	}
+
+	t.Run("should map each resource to its group", func(t *testing.T) {
+		glue, err := compile(false)
+		defer glue.Close()
+
+		assert.NoError(t, err)
+		assert.Len(t, glue.Owners(), 2)
+		assert.Equal(t, "shell", glue.Owners()[0].Group)
+		assert.Equal(t, "tools", glue.Owners()[1].Group)
+
+		// subgroups share the resources of their group
+		assert.Len(t, glue.Conflicts(), 1)
+		assert.Equal(t, "path /home/.zshrc of shell ("+script+":3) overlaps with path /home of tools ("+script+":9)", glue.Conflicts()[0].String())
+	})
+
+	t.Run("should fail on conflicts when strict", func(t *testing.T) {
+		glue, err := compile(true)
+		defer glue.Close()
+
+		assert.ErrorContains(t, err, "Found 1 conflicting resources, nothing was run")
+	})
+}
@@ -0,0 +1,201 @@
+package core
+
+import (
+	"strings"
+
+	"github.com/patrixr/glue/pkg/blueprint"
+	"github.com/patrixr/glue/pkg/runtime"
+	"github.com/patrixr/q"
+)
+
+type GroupOpts struct {
+	Parallel  bool                   `json:"parallel"`
+	DependsOn []string               `json:"depends_on"`
+	Retry     *blueprint.RetryPolicy `json:"retry"`
+	When      any                    `json:"when"`
+	OnError   string                 `json:"on_error"`
+	Phase     string                 `json:"phase"`
+}
+
+// A group which was skipped by the selector
+// It is kept around in case another group depends on it
+type pendingGroup struct {
+	path   []string
+	stack  []*GlueScript
+	parent blueprint.Blueprint
+	invoke func() error
+}
+
+// (internal)
+// Compiles a group into the current blueprint, if allowed by the selector
+func (glue *Glue) compileGroup(R runtime.Runtime, name string, opts GroupOpts, fn runtime.RTFunction) error {
+	allowed, err := glue.canRunGroup(name)
+
+	if err != nil {
+		return err
+	}
+
+	traversed := !allowed && glue.leadsToGroup(name)
+
+	if !allowed && !traversed {
+		glue.pending = append(glue.pending, &pendingGroup{
+			path:   append(glue.Stack.GroupPath(), name),
+			stack:  glue.Stack.snapshot(),
+			parent: glue.BluePrint,
+			invoke: func() error {
+				return glue.compileGroup(R, name, opts, fn)
+			},
+		})
+		return nil
+	}
+
+	glue.Stack.PushGroup(name)
+
+	defer glue.Stack.PopGroup()
+
+	// A traversed group is only compiled to reach the selected groups it contains, its own actions are skipped
+	glue.Stack.CurrentGroup().Traversed = traversed
+	glue.Stack.CurrentGroup().Retry = opts.Retry
+
+	glue.Log.Info("[Group]", "name", name)
+	glue.Fire(EV_GROUP_START, name)
+
+	path := strings.Join(glue.Stack.GroupPath(), GroupSeparator)
+	node := blueprint.NewSerialBlueprint(name)
+	node.Group = path
+	node.OnError = blueprint.ErrorPolicy(opts.OnError)
+	node.Phase, _ = blueprint.ParsePhase(opts.Phase)
+	node.When, err = glue.decodeCondition(opts.When)
+
+	if err != nil {
+		return err
+	}
+
+	if !traversed {
+		node.DependsOn = opts.DependsOn
+		glue.compiled[strings.ToLower(path)] = opts.DependsOn
+	}
+
+	var groupPlan blueprint.Blueprint = node
+
+	if opts.Parallel {
+		parallel := blueprint.NewParallelBlueprint(name, glue.Jobs)
+		parallel.SerialBlueprint = *node
+		groupPlan = parallel
+	}
+
+	basePlan := glue.BluePrint
+	glue.BluePrint = groupPlan
+
+	defer func() {
+		basePlan.Add(groupPlan)
+		glue.BluePrint = basePlan
+	}()
+
+	if err := R.InvokeFunctionSafe(fn); err != nil {
+		return err
+	}
+
+	glue.Fire(EV_GROUP_END, name)
+
+	return nil
+}
+
+// (internal)
+// Compiles the skipped groups which are required by the compiled ones, until all dependencies are met
+func (glue *Glue) compileDependencies() error {
+	for {
+		for _, deps := range glue.compiled {
+			for _, dep := range deps {
+				if _, ok := glue.compiled[strings.ToLower(dep)]; !ok {
+					glue.required = append(glue.required, strings.Split(strings.ToLower(dep), GroupSeparator))
+				}
+			}
+		}
+
+		pending := glue.pending
+		glue.pending = nil
+		progress := false
+
+		for _, group := range pending {
+			if !glue.isRequired(group.path) && !glue.leadsToRequired(group.path) {
+				glue.pending = append(glue.pending, group)
+				continue
+			}
+
+			if err := glue.invokePending(group); err != nil {
+				return err
+			}
+
+			progress = true
+		}
+
+		if !progress {
+			return nil
+		}
+	}
+}
+
+// (internal)
+// Runs a skipped group with the execution stack it was originally declared in
+func (glue *Glue) invokePending(group *pendingGroup) error {
+	stack := glue.Stack.ExecutionStack
+	plan := glue.BluePrint
+
+	glue.Stack.ExecutionStack = group.stack
+	glue.BluePrint = group.parent
+
+	defer func() {
+		glue.Stack.ExecutionStack = stack
+		glue.BluePrint = plan
+	}()
+
+	return group.invoke()
+}
+
+// (internal)
+// Checks if the group is a dependency of a compiled group, or nested in one
+func (glue *Glue) isRequired(path []string) bool {
+	for _, required := range glue.required {
+		if hasPathPrefix(path, required) {
+			return true
+		}
+	}
+	return false
+}
+
+// (internal)
+// Checks if the group contains a dependency of a compiled group
+func (glue *Glue) leadsToRequired(path []string) bool {
+	for _, required := range glue.required {
+		if len(required) > len(path) && hasPathPrefix(required, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// (internal)
+func hasPathPrefix(path []string, prefix []string) bool {
+	if len(prefix) > len(path) {
+		return false
+	}
+
+	for i, name := range prefix {
+		if !strings.EqualFold(name, path[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// (internal)
+// Returns a copy of the execution stack which isn't affected by later pushes and pops
+func (scope *GlueStack) snapshot() []*GlueScript {
+	return q.Map(scope.ExecutionStack, func(script *GlueScript) *GlueScript {
+		clone := *script
+		clone.GroupStack = append([]*GlueCodeGroup{}, script.GroupStack...)
+		return &clone
+	})
+}
@@ -0,0 +1,66 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/patrixr/glue/pkg/blueprint"
+	"github.com/patrixr/glue/pkg/runtime"
+)
+
+// (internal)
+// Compiles the actions of a handler into a blueprint which runs at the end of the plan, when notified
+func (glue *Glue) compileHandler(R runtime.Runtime, name string, fn runtime.RTFunction) error {
+	key := strings.ToLower(name)
+
+	if _, exists := glue.handlers[key]; exists {
+		return fmt.Errorf("Handler %s is already defined", name)
+	}
+
+	glue.Stack.PushGroup(name)
+
+	defer glue.Stack.PopGroup()
+
+	node := blueprint.NewSerialBlueprint(name)
+	node.Group = strings.Join(glue.Stack.GroupPath(), GroupSeparator)
+
+	basePlan := glue.BluePrint
+	glue.BluePrint = node
+
+	defer func() {
+		glue.BluePrint = basePlan
+	}()
+
+	if err := R.InvokeFunctionSafe(fn); err != nil {
+		return err
+	}
+
+	glue.handlers[key] = node
+
+	root := glue.root
+
+	if root == nil {
+		root = basePlan
+	}
+
+	root.AddHandler(node)
+
+	return nil
+}
+
+// (internal)
+// Notifying a handler which was never declared is most likely a typo, or the handler is part of a group which wasn't selected
+func (glue *Glue) warnUnknownHandlers() {
+	warned := map[string]bool{}
+
+	for _, name := range glue.notified {
+		key := strings.ToLower(name)
+
+		if _, ok := glue.handlers[key]; ok || warned[key] {
+			continue
+		}
+
+		warned[key] = true
+		glue.Log.Warn("[Handler]", "unknown", name)
+	}
+}
@@ -0,0 +1,110 @@
+package core
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/patrixr/glue/pkg/blueprint"
+	"github.com/patrixr/glue/pkg/runtime"
+)
+
+// (internal)
+// A value of the script evaluated right before the action using it runs
+type deferredValue struct {
+	fn     runtime.RTFunction
+	script string
+	kind   ScriptType
+}
+
+// (internal)
+// The deferred values of the script, referenced by name within the arguments of actions
+type deferredValues struct {
+	lock   sync.Mutex
+	values map[string]deferredValue
+}
+
+// (internal)
+// Registers a function of the script and returns the pending argument standing for its value
+// The function is evaluated relatively to the script declaring it, e.g for `read` to find relative paths
+func (glue *Glue) deferValue(fn runtime.RTFunction) blueprint.Pending {
+	deferred := &glue.deferred
+
+	deferred.lock.Lock()
+	defer deferred.lock.Unlock()
+
+	if deferred.values == nil {
+		deferred.values = map[string]deferredValue{}
+	}
+
+	value := glue.newDeferredValue(fn)
+	pending := blueprint.Pending{Name: fmt.Sprintf("later-%d", len(deferred.values)+1)}
+	deferred.values[pending.Name] = value
+
+	return pending
+}
+
+// (internal)
+// Captures a function of the script along with the script declaring it
+func (glue *Glue) newDeferredValue(fn runtime.RTFunction) deferredValue {
+	value := deferredValue{fn: fn}
+
+	if glue.Stack.HasActiveScript() {
+		value.script = glue.Stack.ActiveScript().Uri
+		value.kind = glue.Stack.ActiveScript().Type
+	}
+
+	return value
+}
+
+// (internal)
+// Calls the function within the script declaring it
+// Calls are serialized, the actions of parallel groups share the script stack of the instance
+func (glue *Glue) evaluate(value deferredValue) (any, error) {
+	glue.evaluating.Lock()
+	defer glue.evaluating.Unlock()
+
+	if len(value.script) > 0 {
+		glue.Stack.PushScript(value.script, value.kind)
+		defer glue.Stack.PopScript()
+	}
+
+	return glue.Runtime.CallFunction(value.fn)
+}
+
+// (internal)
+// Replaces the pending values of an argument by the result of their function
+func (glue *Glue) resolvePending(val any) (any, error) {
+	return blueprint.ReplaceNative(val, func(val any) (any, bool, error) {
+		pending, ok := blueprint.AsPending(val)
+
+		if !ok {
+			return val, false, nil
+		}
+
+		glue.deferred.lock.Lock()
+		value, found := glue.deferred.values[pending.Name]
+		glue.deferred.lock.Unlock()
+
+		if !found {
+			return nil, true, &unresolvedValue{pending.Name, fmt.Errorf("The value of %s is not available, deferred values are only evaluated by the script declaring them", pending.Name)}
+		}
+
+		res, err := glue.evaluate(value)
+
+		if err != nil {
+			return nil, true, &unresolvedValue{pending.Name, fmt.Errorf("Unable to evaluate %s: %w", pending.Name, err)}
+		}
+
+		return res, true, nil
+	})
+}
+
+// (internal)
+// Reports whether some arguments are only known once the plan runs
+func resolvedLater(args []any) bool {
+	return blueprint.ContainsNative(args, func(val any) bool {
+		_, pending := blueprint.AsPending(val)
+		_, ref := blueprint.AsResultRef(val)
+		return pending || ref
+	})
+}
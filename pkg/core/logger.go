@@ -70,23 +70,23 @@ func (gl *GlueLogger) Errorf(format string, args ...interface{}) {
 }
 
 func CreateLogger() *GlueLogger {
-	writer := CreateGlueWriter(os.Stdout)
-	writerErr := CreateGlueWriter(os.Stderr)
+	logger := &GlueLogger{
+		Stdout: CreateGlueWriter(os.Stdout),
+		Stderr: CreateGlueWriter(os.Stderr),
+	}
 
 	options := log.Options{
 		ReportTimestamp: true,
 	}
 
-	errLog := log.NewWithOptions(writerErr, options)
-	stdLog := log.NewWithOptions(writer, options)
+	// the loggers write through the writers of the GlueLogger, so that Loud and Quiet apply to them
+	logger.errLog = log.NewWithOptions(&logger.Stderr, options)
+	logger.stdLog = log.NewWithOptions(&logger.Stdout, options)
 
-	stdLog.SetColorProfile(termenv.TrueColor)
-	errLog.SetColorProfile(termenv.TrueColor)
+	logger.stdLog.SetColorProfile(termenv.TrueColor)
+	logger.errLog.SetColorProfile(termenv.TrueColor)
 
-	return &GlueLogger{
-		errLog, stdLog,
-		writer, writerErr,
-	}
+	return logger
 }
 
 type GlueWriter struct {
@@ -41,52 +41,265 @@ func InstallNativeGlueModules(glue *Glue) {
 			return nil, nil
 		})
 
+	// @auteur("Groups")
+	//
+	// # Groups
+	//
+	// Groups organize the actions of a script into named units, which can be selected with `glue only <selector>`.
+	// Groups can be nested, in which case they are referenced by their dotted path (e.g. `configs.nvim`).
+	//
+	// An optional table of options can be passed before the group function:
+	//
+	// - `parallel`: run the actions and subgroups of the group concurrently (limited by `--jobs`)
+	// - `depends_on`: a list of groups that must run before this one
+	// - `retry`: a retry policy applied to every action of the group, unless the action defines its own
+	// - `when`: a condition evaluated right before the group runs, see below
+	// - `on_error`: what happens to the rest of the group when one of its actions fails, see below
+	// - `phase`: the phase the group runs in, see below
+	//
+	// ```lua
+	// group("homebrew", function()
+	//   Homebrew({ packages = { "neovim" } })
+	// end)
+	//
+	// group("configs", function()
+	//   group("nvim", { depends_on = { "homebrew" } }, function()
+	//     Copy({ source = "./nvim", dest = "~/.config/nvim" })
+	//   end)
+	// end)
+	// ```
+	//
+	// Groups are ordered according to their dependencies, regardless of where they are declared.
+	// When selecting a group, its dependencies are automatically selected as well, unless `--no-deps` is specified.
+	//
+	// ## Retries
+	//
+	// Any module call accepts a table of options as its last argument. A `retry` policy runs a failing action again,
+	// waiting `delay` before the second attempt and multiplying it by `backoff` after each one:
+	//
+	// ```lua
+	// Sh("brew update", { retry = { attempts = 3, delay = "5s", backoff = 2 } })
+	//
+	// group("homebrew", { retry = { attempts = 3, delay = "10s" } }, function()
+	//   Homebrew({ packages = { "neovim" } })
+	// end)
+	// ```
+	//
+	// ## Timeouts
+	//
+	// The `timeout` option cancels an action which runs for longer than the given duration, failing it:
+	//
+	// ```lua
+	// Homebrew({ packages = { "neovim" } }, { timeout = "10m" })
+	// ```
+	//
+	// Interrupting glue (Ctrl-C or SIGTERM) cancels the running actions in the same way. The remaining steps are skipped
+	// and the report is printed before exiting. Interrupting a second time exits immediately.
+	//
+	// ## Conditions
+	//
+	// Lua `if` statements are evaluated while the plan is compiled, before anything runs. To decide on a step based on the effects
+	// of the previous ones, use the `when` option of groups and module calls. It is evaluated by the blueprint right before running:
+	//
+	// - a string is a shell test run with `sh -c`, which holds if the command exits successfully
+	// - a function holds if it returns a truthy value
+	//
+	// ```lua
+	// HomebrewInstall()
+	// Sh("brew analytics off", { when = "which brew" })
+	//
+	// group("fonts", { when = function() return read("~/.fonts-enabled") == "yes" end }, function()
+	//   Homebrew({ casks = { "font-fira-code" } })
+	// end)
+	// ```
+	//
+	// When a condition doesn't hold, the action (or every action of the group) is reported as skipped.
+	// Plans using function conditions cannot be saved with `--out`, as functions only exist within the script.
+	//
+	// ## Registered results
+	//
+	// A module call with the `register` option returns a handle to its result. The `stdout`, `rc` and `changed` fields
+	// of the handle are resolved when the plan runs, and can be passed to later module calls or used as their condition.
+	// As a condition, `stdout` holds if the action printed something, `rc` if it succeeded and `changed` if it changed anything:
+	//
+	// ```lua
+	// local prefix = Sh({ cmd = "brew --prefix", register = true })
+	//
+	// Blockinfile({ path = "~/.zshrc", block = prefix.stdout, state = true })
+	// Sh("brew doctor", { when = prefix.rc })
+	// ```
+	//
+	// Using the result of an action which didn't run before fails the call, and doesn't satisfy its condition.
+	// The fields are references rather than values, they cannot be concatenated to strings.
+	//
+	// ## Deferred values
+	//
+	// Functions such as `read` run while the plan is compiled, before any action. `later` defers a value until right before
+	// the action using it runs, e.g to read a file created by a previous step:
+	//
+	// ```lua
+	// Copy({ source = "./zsh", dest = "~/.config/zsh" })
+	// Blockinfile({ path = "~/.zshrc", block = later(function() return read("~/.config/zsh/init.zsh") end), state = true })
+	// ```
+	//
+	// The plan shows deferred values as pending. As they are evaluated by the script, plans using them cannot be saved with `--out`.
+	//
+	// ## Errors
+	//
+	// By default, a failing action doesn't prevent the rest of the plan from running. The `on_error` option of a group changes that:
+	//
+	// - `continue`: the remaining steps run regardless of the failure
+	// - `stop-group`: the remaining steps of the group are not run, the rest of the plan is
+	// - `stop`: nothing else runs
+	//
+	// Subgroups use the policy of their parent unless they define their own, and `--fail-fast` applies `stop` to the whole plan.
+	// Failures of a module call can be ignored altogether with `ignore_errors`:
+	//
+	// ```lua
+	// group("macos", { on_error = "stop-group" }, function()
+	//   Sh("defaults write com.apple.dock autohide -bool true")
+	//   Sh("killall Dock", { ignore_errors = true })
+	// end)
+	// ```
+	//
+	// Steps which never ran because of a policy are listed in the report.
+	//
+	// ## Phases
+	//
+	// Plans run in three phases, one after the other: `pre` (or `bootstrap`), `main` and `post`.
+	// Groups and module calls run in the `main` phase unless they, or their enclosing group, use the `phase` option.
+	// Phases don't depend on the order groups are declared in, or on the files they are declared in:
+	//
+	// ```lua
+	// HomebrewInstall({ phase = "bootstrap" })
+	//
+	// group("configs", function()
+	//   Copy({ source = "./nvim", dest = "~/.config/nvim" })
+	// end)
+	//
+	// group("upgrades", { phase = "post" }, function()
+	//   HomebrewUpgrade()
+	// end)
+	// ```
+	//
+	// The enclosing groups of a call moved to another phase are recreated in that phase, with the same conditions and error policy.
+	// A group cannot depend on a group of a later phase. `--plan` shows the phases.
+	//
+	// ## Managed resources
+	//
+	// Modules declare the resources their calls manage: `Copy` the destination, `Blockinfile` the block of its file and `Homebrew`
+	// its packages. Two groups managing the same resource, or a file within a copied folder, are likely to undo each other's changes:
+	//
+	// ```lua
+	// group("shell", function()
+	//   Copy({ source = "./zsh/.zshrc", dest = "~/.zshrc" })
+	// end)
+	//
+	// group("tools", function()
+	//   Blockinfile({ path = "~/.zshrc", block = "eval \"$(mise activate zsh)\"", state = true })
+	// end)
+	// ```
+	//
+	// Such conflicts are reported as warnings once the plan is compiled, and fail it with `--strict-ownership`. A group shares the resources
+	// of its subgroups, and blocks with different markers can share a file. The report lists the group managing each resource.
 	glue.Plug("group", FUNCTION).
 		Brief("Create a runnable group").
 		Arg("name", STRING, "the name of the group to run").
+		Arg("opts?", CustomStruct("GroupOpts", []Field{
+			NewField("parallel?", BOOL, "run the actions and subgroups of the group concurrently"),
+			NewField("depends_on?", TypedArray(STRING), "the groups that must run before this one"),
+			NewField("retry?", retryPolicyType, "the retry policy of the actions of the group"),
+			NewField("when?", ANY, "a function or a shell test evaluated right before the group runs, which is skipped if false"),
+			NewField("on_error?", STRING, "what happens to the rest of the group when an action fails: stop, continue or stop-group"),
+			NewField("phase?", STRING, "the phase the group runs in: pre (or bootstrap), main or post"),
+		}), "the group options").
 		Arg("fn", FUNC, "the function to run when the group is invoked").
 		Do(func(R Runtime, args *Arguments) (RTValue, error) {
 			name := args.EnsureString(0).String()
-			fn := args.EnsureFunction(1)
+			fn := args.EnsureFunction(2)
+			opts := GroupOpts{}
 
-			if len(name) == 0 {
-				return nil, errors.New("Group name cannot be empty")
+			if !args.IsNil(1) {
+				native, err := R.ToNative(args.EnsureDict(1))
+				if err != nil {
+					return nil, err
+				}
+				decoded, err := DecodeNative[GroupOpts](native)
+				if err != nil {
+					return nil, err
+				}
+				opts = decoded
 			}
 
-			if name[0] == NegationRune {
-				return nil, errors.New(fmt.Sprintf("Group name cannot start with character %c", NegationRune))
+			if opts.Retry != nil {
+				if err := opts.Retry.Validate(); err != nil {
+					return nil, err
+				}
 			}
 
-			if strings.EqualFold(name, RootLevel) {
-				return nil, errors.New(fmt.Sprintf("Group cannot be named %s. Reserved keyword", name))
+			if !blueprint.ValidErrorPolicy(opts.OnError) {
+				return nil, fmt.Errorf("Invalid on_error policy '%s'. Expected stop, continue or stop-group", opts.OnError)
 			}
 
-			if allowed, err := glue.canRunGroup(name); !allowed {
+			if _, err := blueprint.ParsePhase(opts.Phase); err != nil {
 				return nil, err
 			}
 
-			glue.Stack.PushGroup(name)
+			if len(name) == 0 {
+				return nil, errors.New("Group name cannot be empty")
+			}
 
-			defer glue.Stack.PopGroup()
+			if name[0] == NegationRune {
+				return nil, errors.New(fmt.Sprintf("Group name cannot start with character %c", NegationRune))
+			}
 
-			glue.Log.Info("[Group]", "name", name)
-			glue.Fire(EV_GROUP_START, name)
+			if strings.EqualFold(name, RootLevel) {
+				return nil, errors.New(fmt.Sprintf("Group cannot be named %s. Reserved keyword", name))
+			}
 
-			groupPlan := blueprint.NewSerialBlueprint(name)
-			basePlan := glue.BluePrint
-			glue.BluePrint = groupPlan
+			return nil, glue.compileGroup(R, name, opts, fn)
+		})
 
-			defer func() {
-				basePlan.Add(groupPlan)
-				glue.BluePrint = basePlan
-			}()
+	// @auteur("Handlers")
+	//
+	// # Handlers
+	//
+	// Handlers are actions which only run when another action changed something, for instance to reload a configuration.
+	// Actions notify handlers by name with the `notify` option, either inlined in the module's table or passed as a last argument:
+	//
+	// ```lua
+	// Blockinfile({ path = "~/.tmux.conf", block = "set -g mouse on", state = true, notify = "reload-tmux" })
+	// Copy({ source = "./tmux", dest = "~/.config/tmux" }, { notify = { "reload-tmux" } })
+	//
+	// handler("reload-tmux", function()
+	//   Sh("tmux source ~/.tmux.conf")
+	// end)
+	// ```
+	//
+	// Handlers run once, at the end of the run, and only if at least one of the actions notifying them reported a change.
+	// They run in the order they are declared, regardless of the order of the notifications.
+	glue.Plug("handler", FUNCTION).
+		Brief("Define actions which only run when notified by a changed action").
+		Arg("name", STRING, "the name actions use to notify the handler").
+		Arg("fn", FUNC, "the function declaring the actions of the handler").
+		Do(func(R Runtime, args *Arguments) (RTValue, error) {
+			name := args.EnsureString(0).String()
+			fn := args.EnsureFunction(1)
 
-			if err := R.InvokeFunctionSafe(fn); err != nil {
-				return nil, err
+			if len(name) == 0 {
+				return nil, errors.New("Handler name cannot be empty")
 			}
 
-			glue.Fire(EV_GROUP_END, name)
+			return nil, glue.compileHandler(R, name, fn)
+		})
 
-			return nil, nil
+	// Defers a value until the action using it runs, see the Deferred values section of the Groups
+	glue.Plug("later", FUNCTION).
+		Brief("Defer a value until the action using it runs").
+		Arg("fn", FUNC, "the function returning the value").
+		Return(DICT, "a pending value, resolved right before the action using it runs").
+		Do(func(R Runtime, args *Arguments) (RTValue, error) {
+			pending := glue.deferValue(args.EnsureFunction(0))
+			return R.FromNative(pending.Native(), DICT)
 		})
 }
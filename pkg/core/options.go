@@ -0,0 +1,215 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"reflect"
+	"strings"
+
+	"github.com/patrixr/glue/pkg/blueprint"
+	"github.com/patrixr/glue/pkg/runtime"
+)
+
+// Options accepted by every module call, as an optional last argument
+//
+// Example:
+//
+//	Sh("brew update", { retry = { attempts = 3, delay = "5s", backoff = 2 } })
+//	Blockinfile({ path = "~/.tmux.conf", block = "...", notify = "reload-tmux" })
+//	Sh("killall Dock", { ignore_errors = true })
+//	Homebrew({ packages = { "neovim" } }, { timeout = "10m" })
+type ActionOpts struct {
+	Retry  *blueprint.RetryPolicy `json:"retry"`
+	Notify []string               `json:"notify"`
+	// A shell test or a function, see decodeCondition
+	When         any    `json:"when"`
+	IgnoreErrors bool   `json:"ignore_errors"`
+	Timeout      string `json:"timeout"`
+	Phase        string `json:"phase"`
+	// Returns a handle to the result of the action, see resultHandle
+	Register bool `json:"register"`
+}
+
+// (internal)
+// The name of the implicit options argument appended to the arguments of every module
+const actionOptsArgName = "options"
+
+// (internal)
+var retryPolicyType = runtime.CustomStruct("RetryPolicy", []runtime.Field{
+	runtime.NewField("attempts", runtime.NUMBER, "the maximum number of times the action is run"),
+	runtime.NewField("delay?", runtime.STRING, "the time to wait before retrying, e.g. \"5s\""),
+	runtime.NewField("backoff?", runtime.NUMBER, "the factor applied to the delay after every attempt"),
+})
+
+// (internal)
+func actionOptsArg() runtime.ArgDef {
+	return runtime.ArgDef{
+		Name: actionOptsArgName,
+		Type: runtime.CustomStruct("ActionOpts", []runtime.Field{
+			runtime.NewField("retry?", retryPolicyType, "attempt the action again when it fails"),
+			runtime.NewField("notify?", runtime.ANY, "the handlers (a name or a list of names) to run if the action changes anything"),
+			runtime.NewField("when?", runtime.ANY, "a function or a shell test evaluated right before the action, which is skipped if false"),
+			runtime.NewField("ignore_errors?", runtime.BOOL, "report failures of the action without failing the run"),
+			runtime.NewField("timeout?", runtime.STRING, "cancel the action when it runs for longer, e.g. \"10m\""),
+			runtime.NewField("phase?", runtime.STRING, "the phase the action runs in: pre (or bootstrap), main or post"),
+			runtime.NewField("register?", runtime.BOOL, "return a handle whose stdout, rc and changed fields hold the result of the action once it ran"),
+		}),
+		Desc:     "options applying to the action",
+		Optional: true,
+	}
+}
+
+// (internal)
+// Converts the arguments of a module call to data, and extracts the options of the action
+// Options are read from the last argument of the call, or from the first table argument of the module
+// (e.g. `Blockinfile({ path = "...", notify = "reload" })`), the former taking precedence
+func collectAction(R runtime.Runtime, mod *GluePlugin, args *runtime.Arguments) ([]any, ActionOpts, error) {
+	data := make([]any, mod.optionsIndex())
+	raw := map[string]interface{}{}
+
+	for i := range data {
+		val, err := R.ToNative(args.Get(i))
+
+		if err != nil {
+			return nil, ActionOpts{}, fmt.Errorf("invalid argument %s. %w", mod.Args[i].Name, err)
+		}
+
+		data[i] = val
+	}
+
+	for _, val := range data {
+		if dict, ok := val.(map[string]any); ok {
+			for key, opt := range dict {
+				if isActionOption(key) {
+					raw[key] = opt
+					delete(dict, key)
+				}
+			}
+			break
+		}
+	}
+
+	explicit, err := R.ToNative(args.Get(mod.optionsIndex()))
+
+	if err != nil {
+		return nil, ActionOpts{}, fmt.Errorf("invalid options. %w", err)
+	}
+
+	if dict, ok := explicit.(map[string]any); ok {
+		for key, opt := range dict {
+			raw[key] = opt
+		}
+	}
+
+	opts, err := runtime.DecodeNative[ActionOpts](raw)
+
+	if err != nil {
+		return nil, opts, fmt.Errorf("invalid options. %w", err)
+	}
+
+	if opts.Retry != nil {
+		if err := opts.Retry.Validate(); err != nil {
+			return nil, opts, err
+		}
+	}
+
+	if _, err := blueprint.ParseTimeout(opts.Timeout); err != nil {
+		return nil, opts, err
+	}
+
+	if _, err := blueprint.ParsePhase(opts.Phase); err != nil {
+		return nil, opts, err
+	}
+
+	return data, opts, nil
+}
+
+// (internal)
+// Checks whether a key of a module table is one of the action options
+func isActionOption(key string) bool {
+	fields := reflect.TypeOf(ActionOpts{})
+
+	for i := 0; i < fields.NumField(); i++ {
+		if strings.EqualFold(fields.Field(i).Tag.Get("json"), key) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// (internal)
+// Returns the retry policy of the innermost group defining one
+func (scope *GlueStack) groupRetry() *blueprint.RetryPolicy {
+	for i := len(scope.ExecutionStack) - 1; i >= 0; i-- {
+		groups := scope.ExecutionStack[i].GroupStack
+
+		for j := len(groups) - 1; j >= 0; j-- {
+			if groups[j].Retry != nil {
+				return groups[j].Retry
+			}
+		}
+	}
+
+	return nil
+}
+
+// (internal)
+// Builds the condition of an action or a group from a `when` option
+// Strings are shell tests which hold when they exit successfully, functions hold when they return a truthy value
+func (glue *Glue) decodeCondition(when any) (*blueprint.Condition, error) {
+	switch val := when.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		condition := &blueprint.Condition{Shell: val}
+		glue.bindCondition(condition)
+		return condition, nil
+	case map[string]any:
+		if ref, ok := blueprint.AsResultRef(val); ok {
+			condition := &blueprint.Condition{Result: &ref}
+			glue.bindCondition(condition)
+			return condition, nil
+		}
+	case runtime.NativeFunction:
+		value := glue.newDeferredValue(val.Fn)
+
+		return &blueprint.Condition{
+			Eval: func(ctx context.Context) (bool, error) {
+				res, err := glue.evaluate(value)
+				return res != nil && res != false, err
+			},
+		}, nil
+	}
+
+	return nil, fmt.Errorf("Invalid condition %v, expected a function, a shell command or a registered result", when)
+}
+
+// (internal)
+// Binds the evaluation of a shell condition, which runs with `sh -c` as it would in an exported script
+// Unlike Machine.Shell, the test keeps its quotes, variables, pipes and lists
+func (glue *Glue) bindCondition(condition *blueprint.Condition) {
+	if condition != nil && condition.Result != nil {
+		glue.bindResultCondition(condition)
+		return
+	}
+
+	if condition == nil || len(condition.Shell) == 0 {
+		return
+	}
+
+	cmd := condition.Shell
+
+	condition.Eval = func(ctx context.Context) (bool, error) {
+		err := exec.CommandContext(ctx, "sh", "-c", cmd).Run()
+
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return false, nil
+		}
+
+		return err == nil, err
+	}
+}
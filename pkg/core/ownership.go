@@ -0,0 +1,185 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/patrixr/glue/pkg/blueprint"
+	"github.com/patrixr/glue/pkg/runtime"
+)
+
+// Lists the resources of the machine a module call manages, such as the files it writes or the packages it installs
+// The function receives the same arguments as the module
+type ResourcesFunc func(R runtime.Runtime, args *runtime.Arguments) ([]blueprint.Resource, error)
+
+// Resources registers a function declaring the resources managed by every call of the module
+// Resources managed by several groups are reported once the plan is compiled
+func (plug *plugin) Resources(fn ResourcesFunc) *plugin {
+	if plug.kind != MODULE {
+		panic("Only Glue modules can manage resources")
+	}
+	plug.resources = fn
+	return plug
+}
+
+// A resource of the machine and the group of the action managing it
+type Owner struct {
+	Resource blueprint.Resource
+	// The dotted path of the group, empty for the root level
+	Group string
+	// The ID of the action, see blueprint.Resolve
+	Action string
+	Source string
+}
+
+// Two groups managing overlapping resources, e.g a file copied by one group and edited by another
+type Conflict struct {
+	First  Owner
+	Second Owner
+}
+
+func (conflict Conflict) String() string {
+	first, second := conflict.First, conflict.Second
+
+	if first.Resource == second.Resource {
+		return fmt.Sprintf("%s is managed by %s and %s", first.Resource, describeOwner(first), describeOwner(second))
+	}
+
+	return fmt.Sprintf("%s of %s overlaps with %s of %s", first.Resource, describeOwner(first), second.Resource, describeOwner(second))
+}
+
+// Owners returns the resources managed by the actions of the compiled plan, in execution order
+func (glue *Glue) Owners() []Owner {
+	return glue.owners
+}
+
+// Conflicts returns the resources of the compiled plan managed by more than one group
+func (glue *Glue) Conflicts() []Conflict {
+	return glue.conflicts
+}
+
+// (internal)
+// Builds the ownership map of the plan, conflicts are logged as warnings or fail the plan with StrictOwnership
+// Arguments only known once the plan runs (registered results and deferred values) are left out
+func (glue *Glue) checkOwnership(plan blueprint.Blueprint) error {
+	glue.owners = nil
+	glue.conflicts = nil
+
+	err := blueprint.Walk(plan, func(node blueprint.Node) error {
+		if !node.Action {
+			return nil
+		}
+
+		mod := glue.findModule(node.Module)
+
+		if mod == nil || mod.resources == nil || resolvedLater(node.Args) {
+			return nil
+		}
+
+		resources, err := glue.actionResources(mod, node.Args, node.Dir)
+
+		if err != nil {
+			return fmt.Errorf("Unable to list the resources of %s [%s]: %w", node.Name, node.ID, err)
+		}
+
+		for _, resource := range resources {
+			glue.own(Owner{Resource: resource, Group: node.Group, Action: node.ID, Source: node.Source})
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return err
+	}
+
+	if len(glue.conflicts) == 0 {
+		return nil
+	}
+
+	if !glue.StrictOwnership {
+		for _, conflict := range glue.conflicts {
+			glue.Log.Warn("[Ownership] " + conflict.String())
+		}
+		return nil
+	}
+
+	problems := make([]string, len(glue.conflicts))
+
+	for i, conflict := range glue.conflicts {
+		problems[i] = "  - " + conflict.String()
+	}
+
+	return fmt.Errorf("Found %d conflicting resources, nothing was run:\n%s", len(problems), strings.Join(problems, "\n"))
+}
+
+// (internal)
+// Records the owner of a resource, and the conflicts with the resources of the other groups
+func (glue *Glue) own(owner Owner) {
+	for _, other := range glue.owners {
+		if other.Resource == owner.Resource && sameOwner(other.Group, owner.Group) {
+			// already owned by the group
+			return
+		}
+	}
+
+	for _, other := range glue.owners {
+		if !sameOwner(other.Group, owner.Group) && other.Resource.Overlaps(owner.Resource) {
+			glue.conflicts = append(glue.conflicts, Conflict{First: other, Second: owner})
+		}
+	}
+
+	glue.owners = append(glue.owners, owner)
+}
+
+// (internal)
+// A group shares the resources of its subgroups, the root level doesn't
+func sameOwner(group string, other string) bool {
+	group = strings.ToLower(group)
+	other = strings.ToLower(other)
+
+	if group == other {
+		return true
+	}
+
+	if len(group) == 0 || len(other) == 0 {
+		return false
+	}
+
+	return strings.HasPrefix(other, group+GroupSeparator) || strings.HasPrefix(group, other+GroupSeparator)
+}
+
+// (internal)
+func (glue *Glue) actionResources(mod *GluePlugin, args []any, dir string) (resources []blueprint.Resource, err error) {
+	R := glue.Runtime
+	values, err := mod.runtimeValues(R, args)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// Runtime errors raised outside of a script are turned into errors
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+
+	return mod.resources(R, runtime.NewArgumentsWithContext(glue.actionContext(dir), R, values))
+}
+
+// (internal)
+// e.g `configs.nvim (glue.lua:12)`
+func describeOwner(owner Owner) string {
+	group := owner.Group
+
+	if len(group) == 0 {
+		group = RootLevel
+	}
+
+	if len(owner.Source) > 0 {
+		return fmt.Sprintf("%s (%s)", group, owner.Source)
+	}
+
+	return group
+}
@@ -1,10 +1,13 @@
 package core
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"strings"
 
 	"github.com/golang-cz/textcase"
+	"github.com/patrixr/glue/pkg/blueprint"
 	"github.com/patrixr/glue/pkg/runtime"
 )
 
@@ -23,8 +26,17 @@ type GluePlugin struct {
 	Args       []runtime.ArgDef
 	ReturnType runtime.Type
 	Kind       PluginKind
+
+	fn        PluginFunc
+	check     PluginFunc
+	coalesce  CoalesceFunc
+	validate  ValidateFunc
+	script    ScriptRenderFunc
+	resources ResourcesFunc
 }
 
+type PluginFunc func(R runtime.Runtime, args *runtime.Arguments) (runtime.RTValue, error)
+
 // An intermediate builder for creating a module
 type plugin struct {
 	name       string
@@ -32,6 +44,11 @@ type plugin struct {
 	kind       PluginKind
 	returnType runtime.Type
 	args       []runtime.ArgDef
+	check      PluginFunc
+	coalesce   CoalesceFunc
+	validate   ValidateFunc
+	script     ScriptRenderFunc
+	resources  ResourcesFunc
 	glue       *Glue
 }
 
@@ -71,11 +88,19 @@ func (plug *plugin) Brief(short string) *plugin {
 	return plug
 }
 
+// Arg declares an argument of the plugin
+// Arguments suffixed with a '?' are optional and may be omitted by the caller
 func (plug *plugin) Arg(name string, valtype runtime.Type, desc string) *plugin {
+	optional := strings.HasSuffix(name, "?")
+	name = strings.TrimSuffix(name, "?")
+
+	runtime.AssertValidSymbolName(name)
+
 	plug.args = append(plug.args, runtime.ArgDef{
-		Name: name,
-		Type: valtype,
-		Desc: desc,
+		Name:     name,
+		Type:     valtype,
+		Desc:     desc,
+		Optional: optional,
 	})
 	return plug
 }
@@ -88,7 +113,17 @@ func (plug *plugin) Return(typ runtime.Type, desc string) *plugin {
 	return plug
 }
 
-func (plug *plugin) Do(fn func(R runtime.Runtime, args *runtime.Arguments) (runtime.RTValue, error)) error {
+// Check registers a function which reports whether the module would change anything, without side effects
+// The function receives the same arguments as the module and should call Changed() on the action scope when appropriate
+func (plug *plugin) Check(fn PluginFunc) *plugin {
+	if plug.kind != MODULE {
+		panic("Only Glue modules can be checked")
+	}
+	plug.check = fn
+	return plug
+}
+
+func (plug *plugin) Do(fn PluginFunc) error {
 	if len(plug.name) == 0 {
 		return errors.New(
 			"Trying to install a module with empty name",
@@ -97,11 +132,31 @@ func (plug *plugin) Do(fn func(R runtime.Runtime, args *runtime.Arguments) (runt
 
 	glue := plug.glue
 	name := plug.name
+	args := plug.args
+
+	if plug.kind == MODULE {
+		// every module accepts options applying to the action itself
+		args = append(args, actionOptsArg())
+	}
+
+	mod := &GluePlugin{
+		Name:       name,
+		Kind:       plug.kind,
+		Brief:      plug.brief,
+		Args:       args,
+		ReturnType: plug.returnType,
+		fn:         fn,
+		check:      plug.check,
+		coalesce:   plug.coalesce,
+		validate:   plug.validate,
+		script:     plug.script,
+		resources:  plug.resources,
+	}
 
 	glue.Runtime.SetFunction(
 		name,
 		plug.brief,
-		plug.args,
+		args,
 		func(R runtime.Runtime, args *runtime.Arguments) runtime.RTValue {
 			if plug.kind == FUNCTION {
 				res, err := fn(R, args)
@@ -111,23 +166,228 @@ func (plug *plugin) Do(fn func(R runtime.Runtime, args *runtime.Arguments) (runt
 				return res
 			}
 
-			glue.BluePrint.Action(name, "", "", func() error {
-				_, err := fn(R, args)
-				return err
-			})
+			if glue.Stack.HasActiveScript() && glue.Stack.CurrentGroup().Traversed {
+				// the action is left out of the plan, references to its result never resolve
+				if _, opts, err := collectAction(R, mod, args); err == nil && opts.Register {
+					return glue.resultHandle(R, glue.registered.name(name))
+				}
+				return nil
+			}
+
+			// Module arguments are stored as data on the blueprint, making it serializable
+			data, opts, err := collectAction(R, mod, args)
+
+			if err != nil {
+				R.RaiseError("%s: %s", name, err.Error())
+			}
+
+			if opts.Retry == nil {
+				opts.Retry = glue.Stack.groupRetry()
+			}
+
+			glue.notified = append(glue.notified, opts.Notify...)
+
+			when, err := glue.decodeCondition(opts.When)
+
+			if err != nil {
+				R.RaiseError("%s: %s", name, err.Error())
+			}
+
+			phase, _ := blueprint.ParsePhase(opts.Phase)
+			register := ""
+
+			if opts.Register {
+				register = glue.registered.name(name)
+			}
+
+			glue.BluePrint.Action(glue.bindModule(mod, blueprint.ActionDef{
+				Name:   name,
+				Group:  strings.Join(glue.Stack.GroupPath(), GroupSeparator),
+				Module: name,
+				Args:   data,
+				Retry:  opts.Retry,
+				Notify: opts.Notify,
+				When:   when,
+
+				IgnoreErrors: opts.IgnoreErrors,
+				Timeout:      opts.Timeout,
+				Phase:        phase,
+				Register:     register,
+				Source:       R.Where(),
+				Dir:          glue.scriptDir(),
+			}))
+
+			if len(register) > 0 {
+				return glue.resultHandle(R, register)
+			}
 
 			return nil
 		})
 
-	mod := &GluePlugin{
-		Name:       name,
-		Kind:       plug.kind,
-		Brief:      plug.brief,
-		Args:       plug.args,
-		ReturnType: plug.returnType,
-	}
-
 	glue.Modules = append(glue.Modules, mod)
 
 	return nil
 }
+
+// BindAction rebuilds the executable functions of a module from its serialized arguments
+func (glue *Glue) BindAction(action blueprint.ActionDef) (blueprint.ActionDef, error) {
+	if len(action.Module) == 0 {
+		// groups are only bound for their condition
+		glue.bindCondition(action.When)
+		return action, nil
+	}
+
+	for _, mod := range glue.Modules {
+		if mod.Name != action.Module {
+			continue
+		}
+
+		if mod.Kind != MODULE {
+			return action, fmt.Errorf("%s is not a module", action.Module)
+		}
+
+		if len(action.Args) != mod.optionsIndex() {
+			return action, fmt.Errorf("%s expects %d arguments, received %d", action.Module, mod.optionsIndex(), len(action.Args))
+		}
+
+		if _, err := blueprint.ParseTimeout(action.Timeout); err != nil {
+			return action, err
+		}
+
+		return glue.bindModule(mod, action), nil
+	}
+
+	return action, fmt.Errorf("Unknown module %s", action.Module)
+}
+
+// (internal)
+// Returns the position of the implicit options argument of a module, which follows its own arguments
+func (mod *GluePlugin) optionsIndex() int {
+	return len(mod.Args) - 1
+}
+
+// (internal)
+// Converts an argument of the module from data back to a runtime value
+func (mod *GluePlugin) runtimeValue(R runtime.Runtime, i int, val any) (runtime.RTValue, error) {
+	typ := mod.Args[i].Type
+
+	if val == nil && mod.Args[i].Optional {
+		typ = runtime.NIL
+	}
+
+	rtval, err := R.FromNative(val, typ)
+
+	if err != nil {
+		return nil, fmt.Errorf("invalid argument %s. %w", mod.Args[i].Name, err)
+	}
+
+	return rtval, nil
+}
+
+// (internal)
+// Converts the arguments of an action from data back to runtime values
+func (mod *GluePlugin) runtimeValues(R runtime.Runtime, args []any) ([]runtime.RTValue, error) {
+	values := make([]runtime.RTValue, len(args))
+
+	for i, val := range args {
+		rtval, err := mod.runtimeValue(R, i, val)
+
+		if err != nil {
+			return nil, err
+		}
+
+		values[i] = rtval
+	}
+
+	return values, nil
+}
+
+// (internal)
+func (glue *Glue) bindModule(mod *GluePlugin, action blueprint.ActionDef) blueprint.ActionDef {
+	if action.When != nil && action.When.Eval == nil {
+		glue.bindCondition(action.When)
+	}
+
+	action.Fn = glue.moduleAction(mod, mod.fn, action, false)
+
+	if mod.check != nil {
+		action.Check = glue.moduleAction(mod, mod.check, action, true)
+	}
+
+	if mod.script != nil {
+		action.Script = glue.moduleScript(mod, action)
+	}
+
+	return action
+}
+
+// (internal)
+// Creates the function that runs a module function with the arguments of the action
+// The result of a registered action is recorded once it ran, checks only preview it
+func (glue *Glue) moduleAction(mod *GluePlugin, fn PluginFunc, action blueprint.ActionDef, preview bool) blueprint.ActionFunc {
+	return func(ctx context.Context, trace *blueprint.Trace) (err error) {
+		R := glue.Runtime
+		values := make([]runtime.RTValue, len(action.Args))
+
+		for i, val := range action.Args {
+			val, err := glue.resolvePending(val)
+
+			if err == nil {
+				val, err = glue.resolveResults(val)
+			}
+
+			// the value is only known once the plan runs, there is nothing to check against
+			var unresolved *unresolvedValue
+			if preview && errors.As(err, &unresolved) {
+				trace.Changed = true
+				trace.Details = fmt.Sprintf("Depends on %s, assuming changes", unresolved.name)
+				return nil
+			}
+
+			if err != nil {
+				return fmt.Errorf("%s: %w", mod.Name, err)
+			}
+
+			if values[i], err = mod.runtimeValue(R, i, val); err != nil {
+				return fmt.Errorf("%s: %w", mod.Name, err)
+			}
+		}
+
+		scope := glue.newActionScope(action.Group)
+		scope.Dir = action.Dir
+		ctx = WithActionScope(ctx, scope)
+
+		var output *captureWriter
+
+		if len(action.Register) > 0 && !preview {
+			output = &captureWriter{Writer: scope.Stdout}
+			scope.Stdout = output
+		}
+
+		defer func() {
+			scope.flush()
+
+			if output != nil {
+				glue.registered.record(action.Register, blueprint.Result{
+					Stdout:  strings.TrimRight(output.captured.String(), "\n"),
+					RC:      exitCode(err),
+					Changed: scope.changed,
+				})
+			}
+
+			trace.Changed = scope.changed
+			trace.Diff = strings.Join(scope.diffs, "")
+			trace.Undo = scope.undo()
+		}()
+
+		// Runtime errors raised outside of a script are turned into action errors
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("%s: %v", mod.Name, r)
+			}
+		}()
+
+		_, err = fn(R, runtime.NewArgumentsWithContext(ctx, R, values))
+		return err
+	}
+}
@@ -0,0 +1,143 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"sync"
+
+	"github.com/patrixr/glue/pkg/blueprint"
+	"github.com/patrixr/glue/pkg/runtime"
+)
+
+// (internal)
+// The results of the registered actions, recorded as they run
+type registry struct {
+	lock    sync.Mutex
+	count   int
+	results map[string]blueprint.Result
+}
+
+// (internal)
+// Names the result of a module call, e.g `sh-2`
+func (reg *registry) name(module string) string {
+	reg.lock.Lock()
+	defer reg.lock.Unlock()
+
+	reg.count++
+	return fmt.Sprintf("%s-%d", strings.ToLower(module), reg.count)
+}
+
+// (internal)
+func (reg *registry) record(name string, result blueprint.Result) {
+	reg.lock.Lock()
+	defer reg.lock.Unlock()
+
+	if reg.results == nil {
+		reg.results = map[string]blueprint.Result{}
+	}
+
+	reg.results[name] = result
+}
+
+// (internal)
+func (reg *registry) lookup(name string) (blueprint.Result, bool) {
+	reg.lock.Lock()
+	defer reg.lock.Unlock()
+
+	result, ok := reg.results[name]
+	return result, ok
+}
+
+// (internal)
+// Returns the handle of a registered module call to the script
+// Each field of the handle is a reference to the result, resolved when the actions using it run
+func (glue *Glue) resultHandle(R runtime.Runtime, name string) runtime.RTValue {
+	handle := map[string]any{}
+
+	for _, field := range blueprint.ResultFields {
+		handle[field] = blueprint.ResultRef{Register: name, Field: field}.Native()
+	}
+
+	val, err := R.FromNative(handle, runtime.DICT)
+
+	if err != nil {
+		R.RaiseError("%s", err.Error())
+	}
+
+	return val
+}
+
+// (internal)
+// An argument which cannot be resolved when the action runs
+// The name is the one of the registered result or of the deferred value
+type unresolvedValue struct {
+	name string
+	err  error
+}
+
+func (err *unresolvedValue) Error() string {
+	return err.err.Error()
+}
+
+// (internal)
+// Replaces the references of an argument by the results they point to
+func (glue *Glue) resolveResults(val any) (any, error) {
+	return blueprint.ReplaceResultRefs(val, func(ref blueprint.ResultRef) (any, error) {
+		result, ok := glue.registered.lookup(ref.Register)
+
+		if !ok {
+			return nil, &unresolvedValue{ref.Register, fmt.Errorf("The result of %s is not available, the action didn't run before this one", ref.Register)}
+		}
+
+		return result.Field(ref.Field)
+	})
+}
+
+// (internal)
+// Binds the evaluation of a condition on a registered result
+// The condition doesn't hold if the registered action didn't run
+func (glue *Glue) bindResultCondition(condition *blueprint.Condition) {
+	ref := *condition.Result
+
+	condition.Eval = func(ctx context.Context) (bool, error) {
+		result, ok := glue.registered.lookup(ref.Register)
+
+		if !ok {
+			return false, nil
+		}
+
+		return result.Holds(ref.Field)
+	}
+}
+
+// (internal)
+// The exit code of a module call, 1 when it failed without one
+func exitCode(err error) int {
+	var exitErr *exec.ExitError
+
+	switch {
+	case err == nil:
+		return 0
+	case errors.As(err, &exitErr):
+		return exitErr.ExitCode()
+	}
+
+	return 1
+}
+
+// (internal)
+// Copies the standard output of an action, while still writing it to the scope
+type captureWriter struct {
+	io.Writer
+	captured bytes.Buffer
+}
+
+func (cw *captureWriter) Write(p []byte) (int, error) {
+	cw.captured.Write(p)
+	return cw.Writer.Write(p)
+}
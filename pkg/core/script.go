@@ -0,0 +1,48 @@
+package core
+
+import (
+	"fmt"
+
+	"github.com/patrixr/glue/pkg/blueprint"
+	"github.com/patrixr/glue/pkg/runtime"
+)
+
+// Renders a module call as POSIX shell commands, for plans exported with `glue --plan --format sh`
+// The function receives the same arguments as the module, see blueprint.ScriptFunc
+type ScriptRenderFunc func(R runtime.Runtime, args *runtime.Arguments) (string, error)
+
+// Script registers a function rendering the calls of the module as shell commands
+// Plans calling modules without a rendering cannot be exported as scripts
+func (plug *plugin) Script(fn ScriptRenderFunc) *plugin {
+	if plug.kind != MODULE {
+		panic("Only Glue modules can be rendered as scripts")
+	}
+	plug.script = fn
+	return plug
+}
+
+// (internal)
+// Creates the function rendering the action with the script function of its module
+func (glue *Glue) moduleScript(mod *GluePlugin, action blueprint.ActionDef) blueprint.ScriptFunc {
+	return func() (script string, err error) {
+		if resolvedLater(action.Args) {
+			return "", fmt.Errorf("%s uses values only known once glue runs the plan (registered results or deferred values)", mod.Name)
+		}
+
+		R := glue.Runtime
+		values, err := mod.runtimeValues(R, action.Args)
+
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", mod.Name, err)
+		}
+
+		// Runtime errors raised outside of a script are turned into export errors
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("%s: %v", mod.Name, r)
+			}
+		}()
+
+		return mod.script(R, runtime.NewArgumentsWithContext(glue.actionContext(action.Dir), R, values))
+	}
+}
@@ -112,6 +112,38 @@ func (selector Selector) Test(levels []string) (bool, error) {
 	return res, nil
 }
 
+// Partial checks if the levels lead to a group selected by one of the filters, without being selected themselves
+// e.g. the filter "group2.internal" is partially matched by the "group2" level
+func (selector Selector) Partial(levels []string) bool {
+	for _, filter := range selector.filters {
+		if len(filter) == 0 || filter[0] == NegationRune {
+			continue
+		}
+
+		path := append([]string{}, selector.prefix...)
+		path = append(path, strings.Split(filter, GroupSeparator)...)
+
+		if len(levels) >= len(path) {
+			continue
+		}
+
+		match := true
+
+		for i, level := range levels {
+			if !nameMatch(path[i], level) {
+				match = false
+				break
+			}
+		}
+
+		if match {
+			return true
+		}
+	}
+
+	return false
+}
+
 func nameMatch(filter string, level string) bool {
 	if strings.Contains(filter, Wildcard) {
 		pattern := strings.ReplaceAll(filter, Wildcard, ".*")
@@ -82,6 +82,26 @@ func TestSelector(t *testing.T) {
 	}
 }
 
+func TestPartialSelector(t *testing.T) {
+	testCases := []struct {
+		selector string
+		levels   []string
+		partial  bool
+	}{
+		{"group2.internal", []string{"group2"}, true},
+		{"group2.internal", []string{"group2", "internal"}, false},
+		{"group2.internal", []string{"group1"}, false},
+		{"group*.internal", []string{"group3"}, true},
+		{"~group2.internal", []string{"group2"}, false},
+		{"group2", []string{"group2"}, false},
+	}
+
+	for _, tc := range testCases {
+		selector := NewSelector(tc.selector)
+		assert.Equal(t, tc.partial, selector.Partial(tc.levels), "Selector(%q).Partial(%v)", tc.selector, tc.levels)
+	}
+}
+
 func TestValidateFilter(t *testing.T) {
 	assert := assert.New(t)
 
@@ -1,6 +1,7 @@
 package core
 
 import (
+	"github.com/patrixr/glue/pkg/blueprint"
 	"github.com/patrixr/q"
 )
 
@@ -15,6 +16,8 @@ const (
 type GlueCodeGroup struct {
 	Name        string
 	Annotations map[string]string
+	Traversed   bool
+	Retry       *blueprint.RetryPolicy
 }
 
 type GlueScript struct {
@@ -110,3 +113,19 @@ func (grp *GlueCodeGroup) Get(key string) (string, bool) {
 	val, ok := grp.Annotations[key]
 	return val, ok
 }
+
+// GroupPath returns the names of all the groups currently open, across scripts
+// The root level of each script is omitted
+func (scope *GlueStack) GroupPath() []string {
+	path := []string{}
+
+	for _, script := range scope.ExecutionStack {
+		for _, group := range script.GroupStack {
+			if group.Name != RootLevel {
+				path = append(path, group.Name)
+			}
+		}
+	}
+
+	return path
+}
@@ -0,0 +1,81 @@
+package core
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+
+	"github.com/patrixr/glue/pkg/blueprint"
+)
+
+// SaveRunState persists the outcome of a run, so that a later run of the same plan can resume it
+func SaveRunState(state blueprint.RunState) error {
+	path, err := runStatePath(state.Script, state.Shape)
+
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(state, "", "  ")
+
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, data, 0644)
+}
+
+// LoadRunState reads the outcome of the last run of a plan
+// Returns nil when the plan, or its current shape, never ran
+func LoadRunState(script string, plan blueprint.Blueprint) (*blueprint.RunState, error) {
+	path, err := runStatePath(script, blueprint.Shape(plan))
+
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	state := &blueprint.RunState{}
+
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, err
+	}
+
+	return state, nil
+}
+
+// (internal)
+// States are keyed by script path and blueprint shape
+func runStatePath(script string, shape string) (string, error) {
+	dir, err := GlueStateDir()
+
+	if err != nil {
+		return "", err
+	}
+
+	abs, err := filepath.Abs(script)
+
+	if err != nil {
+		return "", err
+	}
+
+	key := sha256.Sum256([]byte(abs + "\n" + shape))
+
+	return filepath.Join(dir, "runs", hex.EncodeToString(key[:8])+".json"), nil
+}
@@ -0,0 +1,35 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/patrixr/glue/pkg/blueprint"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_RunState(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+
+	plan := blueprint.NewSerialBlueprint("<root>")
+	plan.Action(blueprint.ActionDef{Name: "Foo", Module: "Foo", Args: []any{"a"}})
+
+	state, err := LoadRunState("glue.lua", plan)
+	assert.NoError(t, err)
+	assert.Nil(t, state, "the plan never ran")
+
+	assert.NoError(t, SaveRunState(blueprint.RunState{
+		Script: "glue.lua",
+		Shape:  blueprint.Shape(plan),
+		Steps:  []blueprint.StepState{{Step: 1, Name: "Foo", Status: blueprint.StatusFailed}},
+	}))
+
+	state, err = LoadRunState("glue.lua", plan)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1}, state.FailedSteps())
+
+	plan.Action(blueprint.ActionDef{Name: "Foo", Module: "Foo", Args: []any{"b"}})
+
+	state, err = LoadRunState("glue.lua", plan)
+	assert.NoError(t, err)
+	assert.Nil(t, state, "states only apply to plans of the same shape")
+}
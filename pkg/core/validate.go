@@ -0,0 +1,155 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/patrixr/glue/pkg/blueprint"
+	"github.com/patrixr/glue/pkg/runtime"
+)
+
+// Checks the arguments of a module call before anything runs
+// The function receives the same arguments as the module, and returns an error describing what is wrong with them
+type ValidateFunc func(R runtime.Runtime, args *runtime.Arguments) error
+
+// Validate registers a function checking the arguments of every call of the module once the plan is compiled
+// A plan with invalid calls doesn't run at all, rather than failing midway
+func (plug *plugin) Validate(fn ValidateFunc) *plugin {
+	if plug.kind != MODULE {
+		panic("Only Glue modules can be validated")
+	}
+	plug.validate = fn
+	return plug
+}
+
+// (internal)
+// Validates every action of the plan, and reports all the invalid ones at once
+// Values only known once the plan runs (registered results and deferred values) are replaced by placeholders,
+// so that the rest of the arguments are still validated
+func (glue *Glue) validatePlan(plan blueprint.Blueprint) error {
+	problems := []string{}
+
+	blueprint.Walk(plan, func(node blueprint.Node) error {
+		if !node.Action {
+			return nil
+		}
+
+		mod := glue.findModule(node.Module)
+
+		if mod == nil || mod.validate == nil {
+			return nil
+		}
+
+		args, placeholders, known := knownArgs(node.Args)
+
+		if !known {
+			return nil
+		}
+
+		if err := glue.validateAction(mod, args, node.Dir); err != nil && !mentionsAny(err.Error(), placeholders) {
+			problems = append(problems, describeProblem(node, err))
+		}
+
+		return nil
+	})
+
+	if len(problems) == 0 {
+		return nil
+	}
+
+	return fmt.Errorf("Found %d invalid actions, nothing was run:\n%s", len(problems), strings.Join(problems, "\n"))
+}
+
+// (internal)
+func (glue *Glue) validateAction(mod *GluePlugin, args []any, dir string) (err error) {
+	R := glue.Runtime
+	values, err := mod.runtimeValues(R, args)
+
+	if err != nil {
+		return err
+	}
+
+	// Runtime errors raised outside of a script are turned into validation errors
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+
+	return mod.validate(R, runtime.NewArgumentsWithContext(glue.actionContext(dir), R, values))
+}
+
+// (internal)
+// Replaces the values only known once the plan runs by placeholders, returned along with the arguments
+// Registered flags and statuses keep their type, other values become strings naming them, e.g `<later-1>`
+// Arguments which are entirely unknown cannot be validated
+func knownArgs(args []any) ([]any, []string, bool) {
+	placeholders := []string{}
+
+	for _, arg := range args {
+		_, pending := blueprint.AsPending(arg)
+		_, ref := blueprint.AsResultRef(arg)
+
+		if pending || ref {
+			return nil, nil, false
+		}
+	}
+
+	replaced, err := blueprint.ReplaceNative(args, func(val any) (any, bool, error) {
+		if pending, ok := blueprint.AsPending(val); ok {
+			placeholder := "<" + pending.Name + ">"
+			placeholders = append(placeholders, placeholder)
+			return placeholder, true, nil
+		}
+
+		ref, ok := blueprint.AsResultRef(val)
+
+		if !ok {
+			return val, false, nil
+		}
+
+		switch ref.Field {
+		case blueprint.ResultChanged:
+			return true, true, nil
+		case blueprint.ResultRC:
+			return 0, true, nil
+		}
+
+		placeholder := "<" + ref.String() + ">"
+		placeholders = append(placeholders, placeholder)
+		return placeholder, true, nil
+	})
+
+	if err != nil {
+		return nil, nil, false
+	}
+
+	return replaced.([]any), placeholders, true
+}
+
+// (internal)
+// Errors about a placeholder are about a value which isn't known yet
+func mentionsAny(message string, placeholders []string) bool {
+	for _, placeholder := range placeholders {
+		if strings.Contains(message, placeholder) {
+			return true
+		}
+	}
+	return false
+}
+
+// (internal)
+// e.g `- configs.nvim (glue.lua:12) Copy: missing dest`
+func describeProblem(node blueprint.Node, err error) string {
+	group := node.Group
+
+	if len(group) == 0 {
+		group = RootLevel
+	}
+
+	if len(node.Source) > 0 {
+		group = fmt.Sprintf("%s (%s)", group, node.Source)
+	}
+
+	return fmt.Sprintf("  - %s %s: %s", group, node.Module, err)
+}
@@ -0,0 +1,438 @@
+package docs
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/patrixr/glue/pkg/blueprint"
+)
+
+const (
+	ChangeAdded    = "added"
+	ChangeRemoved  = "removed"
+	ChangeModified = "modified"
+)
+
+// The differences between two plans, as printed by `glue plan --compare`
+type PlanComparison struct {
+	Changes  []PlanChange `json:"changes"`
+	Added    int          `json:"added"`
+	Removed  int          `json:"removed"`
+	Modified int          `json:"modified"`
+}
+
+// A group, action or handler which differs between two plans
+type PlanChange struct {
+	Change string `json:"change"`
+	Kind   string `json:"kind"`
+	Name   string `json:"name"`
+	Group  string `json:"group,omitempty"`
+	Module string `json:"module,omitempty"`
+	// The key of the node in the old plan, empty when it was added
+	Before string `json:"before,omitempty"`
+	// The key of the node in the new plan, empty when it was removed
+	After  string        `json:"after,omitempty"`
+	Fields []FieldChange `json:"fields,omitempty"`
+}
+
+// A field of a node which differs between two plans, arguments are compared value by value
+type FieldChange struct {
+	// The path of the field, e.g `args[0].dest`
+	Path   string `json:"path"`
+	Before any    `json:"before,omitempty"`
+	After  any    `json:"after,omitempty"`
+}
+
+// HasChanges tells whether the two compared plans differ
+func (comparison PlanComparison) HasChanges() bool {
+	return len(comparison.Changes) > 0
+}
+
+// ReadPlanDocument reads a plan printed with `--plan --format json`, or a bundle saved with `--plan --out`
+func ReadPlanDocument(r io.Reader) (PlanDocument, error) {
+	data, err := io.ReadAll(r)
+
+	if err != nil {
+		return PlanDocument{}, err
+	}
+
+	var probe struct {
+		Root      json.RawMessage `json:"root"`
+		Blueprint json.RawMessage `json:"blueprint"`
+	}
+
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return PlanDocument{}, err
+	}
+
+	if len(probe.Blueprint) > 0 {
+		plan, err := blueprint.Load(bytes.NewReader(data), func(action blueprint.ActionDef) (blueprint.ActionDef, error) {
+			return action, nil
+		})
+
+		if err != nil {
+			return PlanDocument{}, err
+		}
+
+		return NewPlanDocument(plan), nil
+	}
+
+	if len(probe.Root) == 0 {
+		return PlanDocument{}, errors.New("Unrecognized plan file, expected the output of --plan --format json or --plan --out")
+	}
+
+	var doc PlanDocument
+
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return PlanDocument{}, err
+	}
+
+	if doc.Version != PlanDocumentVersion {
+		return PlanDocument{}, fmt.Errorf("Unsupported plan version %d", doc.Version)
+	}
+
+	return doc, nil
+}
+
+// ComparePlans lists the groups and actions added, removed or modified between two plans
+// Actions are matched by key, then compared field by field, or by content when they only moved. The remaining actions of a group are paired
+// by name and module, in order, and reported as modified along with their argument level differences
+func ComparePlans(before PlanDocument, after PlanDocument) PlanComparison {
+	comparison := PlanComparison{Changes: []PlanChange{}}
+
+	oldGroups, oldActions := flattenPlan(before)
+	newGroups, newActions := flattenPlan(after)
+
+	for _, node := range oldGroups {
+		if _, ok := findNode(newGroups, node.Key); !ok {
+			comparison.add(PlanChange{Change: ChangeRemoved, Before: node.Key}, node)
+		}
+	}
+
+	for _, node := range newGroups {
+		old, ok := findNode(oldGroups, node.Key)
+
+		if !ok {
+			comparison.add(PlanChange{Change: ChangeAdded, After: node.Key}, node)
+			continue
+		}
+
+		if fields := compareNodes(old, node); len(fields) > 0 {
+			comparison.add(PlanChange{Change: ChangeModified, Before: old.Key, After: node.Key, Fields: fields}, node)
+		}
+	}
+
+	// actions identical in both plans are taken out first, even if their position changed,
+	// the others are then paired within their group
+	removed := []*PlanNode{}
+	matched := map[*PlanNode]bool{}
+
+	for _, node := range oldActions {
+		if match, ok := findNode(newActions, node.Key); ok && !matched[match] {
+			matched[match] = true
+
+			// the key only covers the position and the arguments of the action
+			if fields := compareNodes(node, match); len(fields) > 0 {
+				comparison.add(PlanChange{Change: ChangeModified, Before: node.Key, After: match.Key, Fields: fields}, match)
+			}
+			continue
+		}
+
+		idx := slices.IndexFunc(newActions, func(candidate *PlanNode) bool {
+			return !matched[candidate] && candidate.Group == node.Group && len(compareNodes(node, candidate)) == 0
+		})
+
+		if idx >= 0 {
+			matched[newActions[idx]] = true
+			continue
+		}
+
+		removed = append(removed, node)
+	}
+
+	for _, node := range removed {
+		idx := slices.IndexFunc(newActions, func(candidate *PlanNode) bool {
+			return !matched[candidate] && candidate.Group == node.Group && candidate.Module == node.Module && candidate.Name == node.Name
+		})
+
+		if idx < 0 {
+			comparison.add(PlanChange{Change: ChangeRemoved, Before: node.Key}, node)
+			continue
+		}
+
+		matched[newActions[idx]] = true
+		comparison.add(PlanChange{Change: ChangeModified, Before: node.Key, After: newActions[idx].Key, Fields: compareNodes(node, newActions[idx])}, newActions[idx])
+	}
+
+	for _, node := range newActions {
+		if !matched[node] {
+			comparison.add(PlanChange{Change: ChangeAdded, After: node.Key}, node)
+		}
+	}
+
+	return comparison
+}
+
+// (internal)
+func (comparison *PlanComparison) add(change PlanChange, node *PlanNode) {
+	change.Kind = node.Kind
+	change.Name = node.Name
+	change.Group = node.Group
+	change.Module = node.Module
+
+	switch change.Change {
+	case ChangeAdded:
+		comparison.Added++
+		change.Fields = nodeFields(node, false)
+	case ChangeRemoved:
+		comparison.Removed++
+		change.Fields = nodeFields(node, true)
+	case ChangeModified:
+		comparison.Modified++
+	}
+
+	comparison.Changes = append(comparison.Changes, change)
+}
+
+// (internal)
+// Splits the plan in its groups and its actions, both in execution order
+func flattenPlan(doc PlanDocument) ([]*PlanNode, []*PlanNode) {
+	groups := []*PlanNode{}
+	actions := []*PlanNode{}
+
+	if doc.Root == nil {
+		return groups, actions
+	}
+
+	doc.Root.walk(func(node *PlanNode) {
+		if node == doc.Root {
+			return
+		}
+
+		if node.Kind == KindAction {
+			actions = append(actions, node)
+		} else {
+			groups = append(groups, node)
+		}
+	})
+
+	return groups, actions
+}
+
+// (internal)
+func findNode(nodes []*PlanNode, key string) (*PlanNode, bool) {
+	for _, node := range nodes {
+		if strings.EqualFold(node.Key, key) {
+			return node, true
+		}
+	}
+	return nil, false
+}
+
+// (internal)
+// The comparable fields of a node, flattened to their paths
+func flattenNode(node *PlanNode) map[string]any {
+	fields := map[string]any{}
+
+	set := func(path string, value any) {
+		if value != nil && !reflect.ValueOf(value).IsZero() {
+			fields[path] = value
+		}
+	}
+
+	set("name", node.Name)
+	set("annotation", node.Annotation)
+	set("details", node.Details)
+	set("parallel", node.Parallel)
+	set("phase", node.Phase)
+	set("when", node.When)
+	set("timeout", node.Timeout)
+	set("ignore_errors", node.IgnoreErrors)
+	set("on_error", node.OnError)
+
+	if node.Retry != nil {
+		set("retry.attempts", node.Retry.Attempts)
+		set("retry.delay", node.Retry.Delay)
+		set("retry.backoff", node.Retry.Backoff)
+	}
+
+	if len(node.DependsOn) > 0 {
+		set("depends_on", strings.Join(node.DependsOn, ", "))
+	}
+
+	if len(node.Notify) > 0 {
+		set("notify", strings.Join(node.Notify, ", "))
+	}
+
+	// normalizes the arguments, as they would be read back from a JSON plan
+	var args []any
+
+	if data, err := json.Marshal(node.Args); err == nil {
+		json.Unmarshal(data, &args)
+	}
+
+	for i, arg := range args {
+		flattenValue(fmt.Sprintf("args[%d]", i), arg, fields)
+	}
+
+	return fields
+}
+
+// (internal)
+func flattenValue(path string, value any, fields map[string]any) {
+	switch v := value.(type) {
+	case map[string]any:
+		if len(v) == 0 {
+			fields[path] = v
+		}
+		for key, item := range v {
+			flattenValue(path+"."+key, item, fields)
+		}
+	case []any:
+		if len(v) == 0 {
+			fields[path] = v
+		}
+		for i, item := range v {
+			flattenValue(fmt.Sprintf("%s[%d]", path, i), item, fields)
+		}
+	default:
+		fields[path] = v
+	}
+}
+
+// (internal)
+func compareNodes(before *PlanNode, after *PlanNode) []FieldChange {
+	previous := flattenNode(before)
+	next := flattenNode(after)
+	changes := []FieldChange{}
+
+	for path, value := range previous {
+		if other, ok := next[path]; !ok || !reflect.DeepEqual(value, other) {
+			changes = append(changes, FieldChange{Path: path, Before: value, After: other})
+		}
+	}
+
+	for path, value := range next {
+		if _, ok := previous[path]; !ok {
+			changes = append(changes, FieldChange{Path: path, After: value})
+		}
+	}
+
+	sortFields(changes)
+
+	return changes
+}
+
+// (internal)
+// The fields of an added or removed node
+func nodeFields(node *PlanNode, removed bool) []FieldChange {
+	changes := []FieldChange{}
+
+	for path, value := range flattenNode(node) {
+		if path == "name" {
+			continue
+		}
+
+		if removed {
+			changes = append(changes, FieldChange{Path: path, Before: value})
+		} else {
+			changes = append(changes, FieldChange{Path: path, After: value})
+		}
+	}
+
+	sortFields(changes)
+
+	return changes
+}
+
+// (internal)
+func sortFields(fields []FieldChange) {
+	sort.Slice(fields, func(i, j int) bool {
+		return fields[i].Path < fields[j].Path
+	})
+}
+
+// PrintComparison renders the differences between two plans, as text or as json
+func PrintComparison(comparison PlanComparison, format string) (string, error) {
+	switch format {
+	case "", "tree":
+		return printComparisonText(comparison), nil
+	case "json":
+		var buf strings.Builder
+		encoder := json.NewEncoder(&buf)
+		encoder.SetEscapeHTML(false)
+		encoder.SetIndent("", "  ")
+		err := encoder.Encode(comparison)
+		return strings.TrimSuffix(buf.String(), "\n"), err
+	}
+
+	return "", fmt.Errorf("Unknown comparison format '%s'. Expected tree or json", format)
+}
+
+// (internal)
+func printComparisonText(comparison PlanComparison) string {
+	if !comparison.HasChanges() {
+		return "No changes, the plans are identical"
+	}
+
+	builder := strings.Builder{}
+
+	symbols := map[string]string{
+		ChangeAdded:    "+",
+		ChangeRemoved:  "-",
+		ChangeModified: "~",
+	}
+
+	for _, change := range comparison.Changes {
+		key := change.After
+
+		if change.Change == ChangeRemoved {
+			key = change.Before
+		}
+
+		title := change.Kind + " " + key
+
+		if change.Kind == KindAction {
+			title = change.Name + " [" + key + "]"
+
+			if change.Change == ChangeModified && change.Before != change.After {
+				title = change.Name + " [" + change.Before + " -> " + change.After + "]"
+			}
+		}
+
+		builder.WriteString(fmt.Sprintf("%s %s\n", symbols[change.Change], title))
+
+		for _, field := range change.Fields {
+			switch {
+			case field.Before == nil:
+				builder.WriteString(fmt.Sprintf("    + %s: %s\n", field.Path, printValue(field.After)))
+			case field.After == nil:
+				builder.WriteString(fmt.Sprintf("    - %s: %s\n", field.Path, printValue(field.Before)))
+			default:
+				builder.WriteString(fmt.Sprintf("    ~ %s: %s -> %s\n", field.Path, printValue(field.Before), printValue(field.After)))
+			}
+		}
+	}
+
+	builder.WriteString(fmt.Sprintf("\n%d added, %d removed, %d modified", comparison.Added, comparison.Removed, comparison.Modified))
+
+	return builder.String()
+}
+
+// (internal)
+func printValue(value any) string {
+	data, err := json.Marshal(value)
+
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+
+	return string(data)
+}
@@ -0,0 +1,86 @@
+package docs
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/patrixr/glue/pkg/blueprint"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ComparePlans(t *testing.T) {
+	noop := func(ctx context.Context, trace *blueprint.Trace) error { return nil }
+
+	build := func(dest string, extra bool) PlanDocument {
+		plan := blueprint.NewSerialBlueprint("<root>")
+		group := blueprint.NewSerialBlueprint("configs")
+		group.Group = "configs"
+
+		if extra {
+			group.Action(blueprint.ActionDef{Name: "Sh", Group: "configs", Module: "Sh", Args: []any{"echo"}, Fn: noop})
+		}
+
+		group.Action(blueprint.ActionDef{Name: "Sh", Group: "configs", Module: "Sh", Args: []any{"ls"}, Fn: noop})
+		group.Action(blueprint.ActionDef{Name: "Copy", Group: "configs", Module: "Copy", Args: []any{map[string]any{"source": "a", "dest": dest}}, Fn: noop})
+		plan.Add(group)
+
+		blueprint.Resolve(plan, false)
+
+		return NewPlanDocument(plan)
+	}
+
+	t.Run("should find no changes between identical plans", func(t *testing.T) {
+		comparison := ComparePlans(build("b", false), build("b", false))
+
+		assert.False(t, comparison.HasChanges())
+	})
+
+	t.Run("should list the added actions and the modified arguments", func(t *testing.T) {
+		comparison := ComparePlans(build("b", false), build("c", true))
+
+		assert.Equal(t, 1, comparison.Added)
+		assert.Equal(t, 0, comparison.Removed)
+		assert.Equal(t, 1, comparison.Modified)
+
+		modified := comparison.Changes[0]
+		assert.Equal(t, ChangeModified, modified.Change)
+		assert.Equal(t, "Copy", modified.Name)
+		assert.Equal(t, []FieldChange{{Path: "args[0].dest", Before: "b", After: "c"}}, modified.Fields)
+
+		added := comparison.Changes[1]
+		assert.Equal(t, ChangeAdded, added.Change)
+		assert.Equal(t, []FieldChange{{Path: "args[0]", After: "echo"}}, added.Fields)
+	})
+
+	t.Run("should list the removed actions", func(t *testing.T) {
+		comparison := ComparePlans(build("b", true), build("b", false))
+
+		assert.Equal(t, 1, comparison.Removed)
+		assert.Len(t, comparison.Changes, 1)
+	})
+
+	t.Run("should list the modified options of actions with the same arguments", func(t *testing.T) {
+		before, after := build("b", false), build("b", false)
+		action := after.Root.Children[0].Children[0]
+		action.When = "test -f ~/.zshrc"
+		action.Retry = &blueprint.RetryPolicy{Attempts: 3}
+
+		comparison := ComparePlans(before, after)
+
+		assert.Equal(t, 1, comparison.Modified)
+		assert.Equal(t, []FieldChange{
+			{Path: "retry.attempts", After: 3},
+			{Path: "when", After: "test -f ~/.zshrc"},
+		}, comparison.Changes[0].Fields)
+	})
+
+	t.Run("should read plans saved as JSON", func(t *testing.T) {
+		out, err := PrintPlan(buildPlan(), "json")
+		assert.NoError(t, err)
+
+		doc, err := ReadPlanDocument(strings.NewReader(out))
+		assert.NoError(t, err)
+		assert.False(t, ComparePlans(doc, NewPlanDocument(buildPlan())).HasChanges())
+	})
+}
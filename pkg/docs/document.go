@@ -60,6 +60,17 @@ func PrintResultReport(glue *core.Glue, results blueprint.Results) string {
 
 	testFailCount := len(tests) - testPassCount
 
+	rolledBack := 0
+	cancelled := 0
+	for _, trace := range results.Traces {
+		if trace.RolledBack {
+			rolledBack++
+		}
+		if trace.Cancelled {
+			cancelled++
+		}
+	}
+
 	var buf bytes.Buffer
 
 	err := templates.ExecuteTemplate(&buf, "report.md.tmpl", struct {
@@ -75,6 +86,15 @@ func PrintResultReport(glue *core.Glue, results blueprint.Results) string {
 		TestFailCount     int
 		TestSkipCount     int
 		SystemIsCompliant bool
+		OkCount           int
+		ChangedCount      int
+		FailedCount       int
+		SkippedCount      int
+		RolledBackCount   int
+		CancelledCount    int
+		TimeElapsedSec    int
+		Resources         []resourceRow
+		Merged            []mergedRow
 	}{
 		Time:              time.Now().Format(time.RFC822),
 		Traces:            results.Traces,
@@ -88,6 +108,123 @@ func PrintResultReport(glue *core.Glue, results blueprint.Results) string {
 		TestFailCount:     testFailCount,
 		TestSkipCount:     0,
 		SystemIsCompliant: results.Success && results.ErrorCount == 0 && testFailCount == 0,
+		OkCount:           results.Count(blueprint.StatusOk),
+		ChangedCount:      results.Count(blueprint.StatusChanged),
+		FailedCount:       results.Count(blueprint.StatusFailed),
+		SkippedCount:      results.Count(blueprint.StatusSkipped),
+		RolledBackCount:   rolledBack,
+		CancelledCount:    cancelled,
+		TimeElapsedSec:    results.TimeElapsedSec,
+		Resources:         resourceRows(glue),
+		Merged:            mergedRows(results),
+	})
+
+	if err != nil {
+		return err.Error()
+	}
+
+	markdown := buf.String()
+
+	prettified, err := glamour.Render(markdown, "auto")
+
+	if err != nil {
+		return markdown
+	}
+
+	return prettified
+}
+
+// (internal)
+// A resource of the report and the group managing it
+type resourceRow struct {
+	Resource string
+	Group    string
+	Action   string
+	// Another group manages the same resource
+	Conflict bool
+}
+
+// (internal)
+func resourceRows(glue *core.Glue) []resourceRow {
+	conflicts := map[core.Owner]bool{}
+
+	for _, conflict := range glue.Conflicts() {
+		conflicts[conflict.First] = true
+		conflicts[conflict.Second] = true
+	}
+
+	rows := []resourceRow{}
+
+	for _, owner := range glue.Owners() {
+		group := owner.Group
+
+		if len(group) == 0 {
+			group = core.RootLevel
+		}
+
+		rows = append(rows, resourceRow{
+			Resource: owner.Resource.String(),
+			Group:    group,
+			Action:   owner.Action,
+			Conflict: conflicts[owner],
+		})
+	}
+
+	return rows
+}
+
+// (internal)
+// A call coalesced into the action of the report, and the group it was made by
+type mergedRow struct {
+	Action  string
+	Module  string
+	Group   string
+	Summary string
+}
+
+// (internal)
+func mergedRows(results blueprint.Results) []mergedRow {
+	rows := []mergedRow{}
+
+	for _, trace := range results.Traces {
+		for _, call := range trace.Merged {
+			rows = append(rows, mergedRow{
+				Action:  trace.ID,
+				Module:  trace.Name,
+				Group:   call.Group,
+				Summary: call.Summary,
+			})
+		}
+	}
+
+	return rows
+}
+
+// PrintCheckReport renders the results of a check run, listing the actions that would change the system
+func PrintCheckReport(results blueprint.Results) string {
+	var buf bytes.Buffer
+
+	includeDiffs := false
+	for _, trace := range results.Traces {
+		if len(trace.Diff) > 0 {
+			includeDiffs = true
+		}
+	}
+
+	err := templates.ExecuteTemplate(&buf, "check.md.tmpl", struct {
+		Time         string
+		Traces       []blueprint.Trace
+		TraceCount   int
+		ChangeCount  int
+		ErrorCount   int
+		IncludeDiffs bool
+	}{
+		Time:         time.Now().Format(time.RFC822),
+		Traces:       results.Traces,
+		TraceCount:   len(results.Traces),
+		ChangeCount:  results.WouldChangeCount(),
+		ErrorCount:   results.ErrorCount,
+		IncludeDiffs: includeDiffs,
 	})
 
 	if err != nil {
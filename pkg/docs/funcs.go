@@ -3,7 +3,9 @@ package docs
 import (
 	"strconv"
 	"text/template"
+	"time"
 
+	"github.com/patrixr/glue/pkg/blueprint"
 	"github.com/patrixr/q"
 )
 
@@ -23,4 +25,23 @@ var funcMap = template.FuncMap{
 	"gt": func(a, b int) bool {
 		return a > b
 	},
+	"attempts": func(attempts []blueprint.Attempt) int {
+		return len(attempts)
+	},
+	"duration": func(d time.Duration) string {
+		return d.Round(time.Millisecond).String()
+	},
+	"status": func(status blueprint.Status) string {
+		switch status {
+		case blueprint.StatusOk:
+			return "✅ ok"
+		case blueprint.StatusChanged:
+			return "✏️ changed"
+		case blueprint.StatusFailed:
+			return "🚩 failed"
+		case blueprint.StatusSkipped:
+			return "⏭️ skipped"
+		}
+		return string(status)
+	},
 }
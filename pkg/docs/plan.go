@@ -0,0 +1,362 @@
+package docs
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/patrixr/glue/pkg/blueprint"
+	"github.com/patrixr/q"
+)
+
+// The formats a plan can be printed in with `--plan --format`
+var PlanFormats = []string{"tree", "json", "mermaid", "dot", "sh"}
+
+const PlanDocumentVersion = 1
+
+// A plan as printed by `--plan --format json`, meant to be read and diffed rather than applied
+type PlanDocument struct {
+	Version int        `json:"version"`
+	Root    *PlanNode  `json:"root"`
+	Edges   []PlanEdge `json:"edges"`
+}
+
+type PlanNode struct {
+	// Actions are identified by their ID, groups by their path
+	Key          string                 `json:"key"`
+	Kind         string                 `json:"kind"`
+	Name         string                 `json:"name"`
+	Group        string                 `json:"group,omitempty"`
+	Module       string                 `json:"module,omitempty"`
+	Args         []any                  `json:"args,omitempty"`
+	Annotation   string                 `json:"annotation,omitempty"`
+	Details      string                 `json:"details,omitempty"`
+	Parallel     bool                   `json:"parallel,omitempty"`
+	Phase        string                 `json:"phase,omitempty"`
+	DependsOn    []string               `json:"depends_on,omitempty"`
+	Notify       []string               `json:"notify,omitempty"`
+	When         string                 `json:"when,omitempty"`
+	Retry        *blueprint.RetryPolicy `json:"retry,omitempty"`
+	Timeout      string                 `json:"timeout,omitempty"`
+	IgnoreErrors bool                   `json:"ignore_errors,omitempty"`
+	OnError      string                 `json:"on_error,omitempty"`
+	Children     []*PlanNode            `json:"children,omitempty"`
+}
+
+// An ordering constraint between two nodes, referenced by key
+type PlanEdge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	// "then" for nodes of a serial group, "depends_on" for group dependencies and "notify" for handlers
+	Kind string `json:"kind"`
+}
+
+const (
+	KindGroup   = "group"
+	KindAction  = "action"
+	KindHandler = "handler"
+)
+
+// NewPlanDocument describes the blueprint, its nodes and the ordering edges between them
+func NewPlanDocument(plan blueprint.Blueprint) PlanDocument {
+	doc := PlanDocument{
+		Version: PlanDocumentVersion,
+		Root:    newPlanNode(plan, "", true),
+	}
+
+	groups := map[string]*PlanNode{}
+	handlers := map[string]*PlanNode{}
+
+	doc.Root.walk(func(node *PlanNode) {
+		switch node.Kind {
+		case KindGroup:
+			groups[strings.ToLower(node.Group)] = node
+		case KindHandler:
+			handlers[strings.ToLower(node.Name)] = node
+		}
+	})
+
+	doc.Root.walk(func(node *PlanNode) {
+		if !node.Parallel {
+			for i := 1; i < len(node.Children); i++ {
+				if node.Children[i].Kind != KindHandler {
+					doc.Edges = append(doc.Edges, PlanEdge{From: node.Children[i-1].Key, To: node.Children[i].Key, Kind: "then"})
+				}
+			}
+		}
+
+		for _, dep := range node.DependsOn {
+			if group, ok := groups[strings.ToLower(dep)]; ok {
+				doc.Edges = append(doc.Edges, PlanEdge{From: group.Key, To: node.Key, Kind: "depends_on"})
+			}
+		}
+
+		for _, name := range node.Notify {
+			if handler, ok := handlers[strings.ToLower(name)]; ok {
+				doc.Edges = append(doc.Edges, PlanEdge{From: node.Key, To: handler.Key, Kind: "notify"})
+			}
+		}
+	})
+
+	return doc
+}
+
+// (internal)
+// Groups without a path of their own, such as phases, are keyed after their parent
+func newPlanNode(bp blueprint.Blueprint, parent string, root bool) *PlanNode {
+	info := bp.Info()
+
+	node := &PlanNode{
+		Key:          info.ID,
+		Kind:         KindAction,
+		Name:         info.Name,
+		Group:        info.Group,
+		Module:       info.Module,
+		Args:         info.Args,
+		Annotation:   info.Annotation,
+		Details:      info.Details,
+		Parallel:     info.Parallel,
+		Phase:        string(info.Phase),
+		DependsOn:    info.DependsOn,
+		Notify:       info.Notify,
+		Retry:        info.Retry,
+		Timeout:      info.Timeout,
+		IgnoreErrors: info.IgnoreErrors,
+		OnError:      string(info.OnError),
+	}
+
+	if info.When != nil {
+		node.When = info.When.Source()
+	}
+
+	if !info.Action {
+		node.Kind = KindGroup
+		node.Key = info.Group
+
+		if info.Handler {
+			node.Kind = KindHandler
+		}
+
+		if len(node.Key) == 0 {
+			node.Key = strings.TrimPrefix(parent+"/"+info.Name, "/")
+		}
+	}
+
+	prefix := node.Key
+
+	if root {
+		prefix = ""
+	}
+
+	for _, child := range bp.Nested() {
+		node.Children = append(node.Children, newPlanNode(child, prefix, false))
+	}
+
+	return node
+}
+
+// (internal)
+func (node *PlanNode) walk(fn func(node *PlanNode)) {
+	fn(node)
+
+	for _, child := range node.Children {
+		child.walk(fn)
+	}
+}
+
+// PrintPlan renders the blueprint in one of the PlanFormats
+func PrintPlan(plan blueprint.Blueprint, format string) (string, error) {
+	switch format {
+	case "", "tree":
+		return plan.PrettyPrint(), nil
+	case "json":
+		var buf strings.Builder
+		encoder := json.NewEncoder(&buf)
+		encoder.SetEscapeHTML(false)
+		encoder.SetIndent("", "  ")
+		err := encoder.Encode(NewPlanDocument(plan))
+		return strings.TrimSuffix(buf.String(), "\n"), err
+	case "mermaid":
+		return printMermaid(NewPlanDocument(plan)), nil
+	case "dot":
+		return printDot(NewPlanDocument(plan)), nil
+	case "sh":
+		script, err := blueprint.ExportScript(plan)
+		return strings.TrimSuffix(script, "\n"), err
+	}
+
+	return "", fmt.Errorf("Unknown plan format '%s'. Expected one of %s", format, strings.Join(PlanFormats, ", "))
+}
+
+// ValidPlanFormat checks whether the plan can be printed in the given format
+func ValidPlanFormat(format string) bool {
+	return len(format) == 0 || slices.Contains(PlanFormats, format)
+}
+
+// (internal)
+// Names the nodes of the document with identifiers safe for graph languages
+func graphIDs(doc PlanDocument) map[string]string {
+	ids := map[string]string{}
+
+	doc.Root.walk(func(node *PlanNode) {
+		ids[node.Key] = fmt.Sprintf("n%d", len(ids))
+	})
+
+	return ids
+}
+
+// (internal)
+// The label of a node, listing its arguments and annotation
+func (node *PlanNode) label() []string {
+	lines := []string{node.Name}
+
+	if node.Kind == KindHandler {
+		lines[0] += " (handler)"
+	}
+
+	if node.Kind == KindGroup && len(node.Group) == 0 && len(node.Phase) > 0 {
+		lines[0] += " (phase)"
+	}
+
+	if len(node.When) > 0 {
+		lines = append(lines, "when "+node.When)
+	}
+
+	if len(node.Args) > 0 {
+		// the graph formats escape the labels themselves
+		var buf strings.Builder
+		encoder := json.NewEncoder(&buf)
+		encoder.SetEscapeHTML(false)
+
+		if err := encoder.Encode(node.Args); err == nil {
+			lines = append(lines, q.Ellipsis(strings.TrimSuffix(buf.String(), "\n"), 60))
+		}
+	}
+
+	if len(node.Annotation) > 0 {
+		lines = append(lines, node.Annotation)
+	}
+
+	return lines
+}
+
+// (internal)
+func printMermaid(doc PlanDocument) string {
+	ids := graphIDs(doc)
+	builder := strings.Builder{}
+
+	escape := func(lines []string) string {
+		text := strings.Join(lines, "<br/>")
+		return strings.NewReplacer(`"`, "#quot;", "<br/>", "<br/>", "<", "#lt;", ">", "#gt;").Replace(text)
+	}
+
+	var render func(node *PlanNode, depth int)
+
+	render = func(node *PlanNode, depth int) {
+		indent := strings.Repeat("  ", depth)
+
+		if node.Kind == KindAction {
+			builder.WriteString(fmt.Sprintf("%s%s[\"%s\"]\n", indent, ids[node.Key], escape(node.label())))
+			return
+		}
+
+		builder.WriteString(fmt.Sprintf("%ssubgraph %s[\"%s\"]\n", indent, ids[node.Key], escape(node.label())))
+
+		for _, child := range node.Children {
+			render(child, depth+1)
+		}
+
+		builder.WriteString(indent + "end\n")
+	}
+
+	builder.WriteString("flowchart TD\n")
+	render(doc.Root, 1)
+
+	for _, edge := range doc.Edges {
+		arrow := "-->"
+
+		switch edge.Kind {
+		case "depends_on":
+			arrow = "-. depends on .->"
+		case "notify":
+			arrow = "-. notify .->"
+		}
+
+		builder.WriteString(fmt.Sprintf("  %s %s %s\n", ids[edge.From], arrow, ids[edge.To]))
+	}
+
+	return builder.String()
+}
+
+// (internal)
+// Groups are rendered as clusters, edges between groups are attached to an invisible anchor within them
+func printDot(doc PlanDocument) string {
+	ids := graphIDs(doc)
+	builder := strings.Builder{}
+
+	quote := func(lines []string) string {
+		text := strings.Join(lines, "\n")
+		return "\"" + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(text) + "\""
+	}
+
+	kinds := map[string]string{}
+
+	var render func(node *PlanNode, depth int)
+
+	render = func(node *PlanNode, depth int) {
+		indent := strings.Repeat("  ", depth)
+		kinds[node.Key] = node.Kind
+
+		if node.Kind == KindAction {
+			builder.WriteString(fmt.Sprintf("%s%s [label=%s];\n", indent, ids[node.Key], quote(node.label())))
+			return
+		}
+
+		builder.WriteString(fmt.Sprintf("%ssubgraph cluster_%s {\n", indent, ids[node.Key]))
+		builder.WriteString(fmt.Sprintf("%s  label=%s;\n", indent, quote(node.label())))
+		builder.WriteString(fmt.Sprintf("%s  %s [shape=point, style=invis];\n", indent, ids[node.Key]))
+
+		for _, child := range node.Children {
+			render(child, depth+1)
+		}
+
+		builder.WriteString(indent + "}\n")
+	}
+
+	builder.WriteString("digraph plan {\n")
+	builder.WriteString("  compound=true;\n")
+	builder.WriteString("  node [shape=box];\n")
+	render(doc.Root, 1)
+
+	for _, edge := range doc.Edges {
+		attrs := []string{}
+
+		if kinds[edge.From] != KindAction {
+			attrs = append(attrs, "ltail=cluster_"+ids[edge.From])
+		}
+
+		if kinds[edge.To] != KindAction {
+			attrs = append(attrs, "lhead=cluster_"+ids[edge.To])
+		}
+
+		switch edge.Kind {
+		case "depends_on":
+			attrs = append(attrs, `style=dashed`, `label="depends on"`)
+		case "notify":
+			attrs = append(attrs, `style=dotted`, `label="notify"`)
+		}
+
+		line := fmt.Sprintf("  %s -> %s", ids[edge.From], ids[edge.To])
+
+		if len(attrs) > 0 {
+			line += " [" + strings.Join(attrs, ", ") + "]"
+		}
+
+		builder.WriteString(line + ";\n")
+	}
+
+	builder.WriteString("}\n")
+
+	return builder.String()
+}
@@ -0,0 +1,112 @@
+package docs
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/patrixr/glue/pkg/blueprint"
+	"github.com/stretchr/testify/assert"
+)
+
+func buildPlan() blueprint.Blueprint {
+	noop := func(ctx context.Context, trace *blueprint.Trace) error { return nil }
+
+	plan := blueprint.NewSerialBlueprint("<root>")
+
+	base := blueprint.NewSerialBlueprint("base")
+	base.Group = "base"
+	base.Action(blueprint.ActionDef{Name: "Sh", Group: "base", Module: "Sh", Args: []any{"echo a"}, Fn: noop})
+	plan.Add(base)
+
+	configs := blueprint.NewSerialBlueprint("configs")
+	configs.Group = "configs"
+	configs.Annotation = "Dotfiles"
+	configs.DependsOn = []string{"base"}
+	configs.Action(blueprint.ActionDef{Name: "Copy", Group: "configs", Module: "Copy", Args: []any{map[string]any{"source": "a", "dest": "b"}}, Notify: []string{"reload"}, Fn: noop})
+	plan.Add(configs)
+
+	handler := blueprint.NewSerialBlueprint("reload")
+	handler.Group = "reload"
+	handler.Action(blueprint.ActionDef{Name: "Sh", Group: "reload", Module: "Sh", Args: []any{"reload"}, Fn: noop})
+	plan.AddHandler(handler)
+
+	blueprint.Resolve(plan, false)
+
+	return plan
+}
+
+func Test_PlanDocument(t *testing.T) {
+	doc := NewPlanDocument(buildPlan())
+
+	t.Run("should describe groups, actions and handlers", func(t *testing.T) {
+		assert.Equal(t, KindGroup, doc.Root.Kind)
+		assert.Len(t, doc.Root.Children, 3)
+
+		configs := doc.Root.Children[1]
+		assert.Equal(t, "configs", configs.Key)
+		assert.Equal(t, "Dotfiles", configs.Annotation)
+		assert.Equal(t, KindAction, configs.Children[0].Kind)
+		assert.Equal(t, "Copy", configs.Children[0].Module)
+		assert.Equal(t, KindHandler, doc.Root.Children[2].Kind)
+	})
+
+	t.Run("should list the ordering edges", func(t *testing.T) {
+		copyID := doc.Root.Children[1].Children[0].Key
+
+		assert.Equal(t, []PlanEdge{
+			{From: "base", To: "configs", Kind: "then"},
+			{From: "base", To: "configs", Kind: "depends_on"},
+			{From: copyID, To: "reload", Kind: "notify"},
+		}, doc.Edges)
+	})
+}
+
+func Test_PrintPlan(t *testing.T) {
+	plan := buildPlan()
+
+	t.Run("should print valid JSON", func(t *testing.T) {
+		out, err := PrintPlan(plan, "json")
+		assert.NoError(t, err)
+
+		var doc PlanDocument
+		assert.NoError(t, json.Unmarshal([]byte(out), &doc))
+		assert.Equal(t, PlanDocumentVersion, doc.Version)
+	})
+
+	t.Run("should print graphs", func(t *testing.T) {
+		mermaid, err := PrintPlan(plan, "mermaid")
+		assert.NoError(t, err)
+		assert.True(t, strings.HasPrefix(mermaid, "flowchart TD\n"))
+		assert.Contains(t, mermaid, "n1 -. depends on .-> n3")
+
+		dot, err := PrintPlan(plan, "dot")
+		assert.NoError(t, err)
+		assert.Contains(t, dot, "n1 -> n3 [ltail=cluster_n1, lhead=cluster_n3, style=dashed, label=\"depends on\"];")
+	})
+
+	t.Run("should not escape the arguments of graph labels as HTML", func(t *testing.T) {
+		plan := blueprint.NewSerialBlueprint("<root>")
+		plan.Action(blueprint.ActionDef{Name: "Sh", Module: "Sh", Args: []any{"echo <x> & done"}})
+		blueprint.Resolve(plan, false)
+
+		mermaid, err := PrintPlan(plan, "mermaid")
+		assert.NoError(t, err)
+		assert.Contains(t, mermaid, `#quot;echo #lt;x#gt; & done#quot;`)
+
+		dot, err := PrintPlan(plan, "dot")
+		assert.NoError(t, err)
+		assert.Contains(t, dot, `\"echo <x> & done\"`)
+	})
+
+	t.Run("should only export plans rendered by their modules as scripts", func(t *testing.T) {
+		_, err := PrintPlan(plan, "sh")
+		assert.ErrorContains(t, err, "Copy has no shell rendering")
+	})
+
+	t.Run("should reject unknown formats", func(t *testing.T) {
+		_, err := PrintPlan(plan, "xml")
+		assert.Error(t, err)
+	})
+}
@@ -1,12 +1,15 @@
 package machine
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
 	"os"
 	"os/exec"
 	"runtime"
+	"slices"
+	"strings"
 	"time"
 )
 
@@ -18,6 +21,52 @@ type HomebrewParams struct {
 	Whalebrews []string `json:"whalebrews"`
 }
 
+// Merge returns the dependencies of both bundles, without duplicates
+func (params HomebrewParams) Merge(other HomebrewParams) HomebrewParams {
+	return HomebrewParams{
+		Packages:   mergeRows(params.Packages, other.Packages),
+		Casks:      mergeRows(params.Casks, other.Casks),
+		Taps:       mergeRows(params.Taps, other.Taps),
+		Mas:        mergeRows(params.Mas, other.Mas),
+		Whalebrews: mergeRows(params.Whalebrews, other.Whalebrews),
+	}
+}
+
+// (internal)
+func mergeRows(rows []string, others []string) []string {
+	merged := append([]string{}, rows...)
+
+	for _, row := range others {
+		if !slices.Contains(merged, row) {
+			merged = append(merged, row)
+		}
+	}
+
+	return merged
+}
+
+// Brewfile returns the dependencies of the bundle in the format of `brew bundle`
+func (params HomebrewParams) Brewfile() string {
+	var builder strings.Builder
+
+	for _, rows := range []struct {
+		kind  string
+		names []string
+	}{
+		{"brew", params.Packages},
+		{"cask", params.Casks},
+		{"tap", params.Taps},
+		{"mas", params.Mas},
+		{"whalebrew", params.Whalebrews},
+	} {
+		for _, name := range rows.names {
+			builder.WriteString(fmt.Sprintf("%s \"%s\"\n", rows.kind, name))
+		}
+	}
+
+	return builder.String()
+}
+
 type Row struct {
 	kind string
 	name string
@@ -30,8 +79,8 @@ type Homebrew struct {
 	stderr  io.Writer
 }
 
-func HomebrewBundle(m Machine, params HomebrewParams, stdout io.Writer, stderr io.Writer) error {
-	tmp, close, err := m.TempFile(".glue_brewfile_" + time.Now().Format("20060102150405"))
+func HomebrewBundle(ctx context.Context, m Machine, params HomebrewParams, stdout io.Writer, stderr io.Writer) error {
+	brewfile, close, err := writeBrewfile(m, params)
 
 	if err != nil {
 		return err
@@ -39,31 +88,49 @@ func HomebrewBundle(m Machine, params HomebrewParams, stdout io.Writer, stderr i
 
 	defer close()
 
-	for _, row := range params.Packages {
-		tmp.Write([]byte(fmt.Sprintf("brew \"%s\"\n", row)))
-	}
+	return m.Shell(ctx, fmt.Sprintf("brew bundle --file=%s --no-lock", brewfile), stdout, stderr)
+}
 
-	for _, row := range params.Casks {
-		tmp.Write([]byte(fmt.Sprintf("cask \"%s\"\n", row)))
+// HomebrewBundleCheck reports whether some of the bundle's dependencies are not installed yet
+func HomebrewBundleCheck(ctx context.Context, m Machine, params HomebrewParams, stdout io.Writer, stderr io.Writer) (bool, error) {
+	brewfile, close, err := writeBrewfile(m, params)
+
+	if err != nil {
+		return false, err
 	}
 
-	for _, row := range params.Taps {
-		tmp.Write([]byte(fmt.Sprintf("tap \"%s\"\n", row)))
+	defer close()
+
+	err = m.Shell(ctx, fmt.Sprintf("brew bundle check --file=%s --no-upgrade --no-lock", brewfile), stdout, stderr)
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		// brew exits with a non-zero code when dependencies are missing
+		return true, nil
 	}
 
-	for _, row := range params.Mas {
-		tmp.Write([]byte(fmt.Sprintf("mas \"%s\"\n", row)))
+	return false, err
+}
+
+// (internal)
+// Writes the Brewfile of the bundle to a temporary file, returning its path
+func writeBrewfile(m Machine, params HomebrewParams) (string, func() error, error) {
+	tmp, close, err := m.TempFile(".glue_brewfile_" + time.Now().Format("20060102150405"))
+
+	if err != nil {
+		return "", nil, err
 	}
 
-	for _, row := range params.Whalebrews {
-		tmp.Write([]byte(fmt.Sprintf("whalebrew \"%s\"\n", row)))
+	if _, err := tmp.Write([]byte(params.Brewfile())); err != nil {
+		close()
+		return "", nil, err
 	}
 
-	return m.Shell(fmt.Sprintf("brew bundle --file=%s --no-lock", tmp.Name()), stdout, stderr)
+	return tmp.Name(), close, nil
 }
 
-func HomebrewUpgrade(m Machine, stdout io.Writer, stderr io.Writer) error {
-	return m.Shell("brew upgrade", stdout, stderr)
+func HomebrewUpgrade(ctx context.Context, m Machine, stdout io.Writer, stderr io.Writer) error {
+	return m.Shell(ctx, "brew upgrade", stdout, stderr)
 }
 
 func GetHomebrewBin() (string, error) {
@@ -94,7 +161,10 @@ func IsHomebrewInstalled(m Machine) bool {
 	return err == nil && path != ""
 }
 
-func InstallHomebrew(m Machine, stdout io.Writer, stderr io.Writer) error {
+// The official installation command of Homebrew
+const HomebrewInstallCommand = `/bin/bash -c "$(curl -fsSL https://raw.githubusercontent.com/Homebrew/install/HEAD/install.sh)"`
+
+func InstallHomebrew(ctx context.Context, m Machine, stdout io.Writer, stderr io.Writer) error {
 	if runtime.GOOS != "darwin" && runtime.GOOS != "linux" {
 		return errors.New("Homebrew is only supported on macOS and Linux")
 	}
@@ -104,21 +174,20 @@ func InstallHomebrew(m Machine, stdout io.Writer, stderr io.Writer) error {
 		return nil
 	}
 
-	installCommand := `/bin/bash -c "$(curl -fsSL https://raw.githubusercontent.com/Homebrew/install/HEAD/install.sh)"`
-
 	return m.Shell(
-		fmt.Sprintf("bash -c \"%s\"", installCommand),
+		ctx,
+		fmt.Sprintf("bash -c \"%s\"", HomebrewInstallCommand),
 		stdout,
 		stderr,
 	)
 }
 
-func UpdateHomebrew(m Machine, stdout io.Writer, stderr io.Writer) error {
+func UpdateHomebrew(ctx context.Context, m Machine, stdout io.Writer, stderr io.Writer) error {
 	path, err := GetHomebrewBin()
 
 	if err != nil {
 		return err
 	}
 
-	return m.Shell(path+" update", stdout, stderr)
+	return m.Shell(ctx, path+" update", stdout, stderr)
 }
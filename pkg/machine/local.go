@@ -1,12 +1,18 @@
 package machine
 
 import (
+	"context"
 	"io"
 	"os"
 	"os/exec"
 	"strings"
+	"time"
 )
 
+// (internal)
+// How long an interrupted command is given to exit before being killed
+const shutdownGracePeriod = 10 * time.Second
+
 type LocalMachine struct {
 }
 
@@ -14,13 +20,19 @@ func NewLocalMachine() Machine {
 	return &LocalMachine{}
 }
 
-func (m *LocalMachine) Shell(input string, stdout io.Writer, stderr io.Writer) error {
+func (m *LocalMachine) Shell(ctx context.Context, input string, stdout io.Writer, stderr io.Writer) error {
 	args := strings.Fields(input)
-	cmd := exec.Command(args[0], args[1:]...)
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
 
 	cmd.Stdout = stdout
 	cmd.Stderr = stderr
 
+	// give the command a chance to clean up before killing it
+	cmd.Cancel = func() error {
+		return cmd.Process.Signal(os.Interrupt)
+	}
+	cmd.WaitDelay = shutdownGracePeriod
+
 	return cmd.Run()
 }
 
@@ -1,11 +1,13 @@
 package machine
 
 import (
+	"context"
 	"io"
 )
 
 type Machine interface {
-	Shell(input string, stdout io.Writer, stderr io.Writer) error
+	// Shell runs a command, which is interrupted when the context is cancelled
+	Shell(ctx context.Context, input string, stdout io.Writer, stderr io.Writer) error
 	TempFile(name string) (File, func() error, error)
 }
 
@@ -36,8 +36,22 @@ func init() {
 		glue.Plug("backup", core.MODULE).
 			Brief("Creates a backup of a file").
 			Arg("path", STRING, "the file to create a backup of").
+			Check(func(R Runtime, args *Arguments) (RTValue, error) {
+				str := args.EnsureString(0)
+
+				changes, err := BackupChanges(str.String())
+
+				if err != nil {
+					return nil, err
+				}
+
+				// a new backup is created on every run
+				recordChanges(glue.Scope(args), changes)
+				return nil, nil
+			}).
 			Do(func(R Runtime, args *Arguments) (RTValue, error) {
 				str := args.EnsureString(0)
+				glue.Scope(args).Changed()
 				return nil, Backup(str.String())
 			})
 
@@ -45,7 +59,9 @@ func init() {
 	})
 }
 
-func Backup(originalPath string) error {
+// (internal)
+// Ensures the path points to a file that can be backed up
+func ensureBackupable(originalPath string) error {
 	stat, err := os.Stat(originalPath)
 
 	if err != nil {
@@ -56,10 +72,25 @@ func Backup(originalPath string) error {
 		return errors.New("Cannot create backup of a folder: " + originalPath)
 	}
 
-	dir := filepath.Dir(originalPath)
-	name := filepath.Base(originalPath)
-	backupName := name + ".backup." + time.Now().Format(time.RFC3339)
-	backupPath := filepath.Join(dir, backupName)
+	return nil
+}
+
+func Backup(originalPath string) error {
+	return backupTo(originalPath, backupPath(originalPath))
+}
+
+// (internal)
+// Copies the file to the backup path
+func backupTo(originalPath string, backup string) error {
+	if err := ensureBackupable(originalPath); err != nil {
+		return err
+	}
+
+	stat, err := os.Stat(originalPath)
+
+	if err != nil {
+		return err
+	}
 
 	content, err := os.ReadFile(originalPath)
 
@@ -67,9 +98,133 @@ func Backup(originalPath string) error {
 		return err
 	}
 
-	if err = os.WriteFile(backupPath, content, stat.Mode()); err != nil {
+	if err = os.WriteFile(backup, content, stat.Mode()); err != nil {
 		return err
 	}
 
 	return nil
 }
+
+// BackupChanges lists the files Backup would create, without touching them
+func BackupChanges(originalPath string) ([]FileChange, error) {
+	if err := ensureBackupable(originalPath); err != nil {
+		return nil, err
+	}
+
+	content, err := os.ReadFile(originalPath)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return []FileChange{{Path: backupPath(originalPath), After: string(content)}}, nil
+}
+
+// (internal)
+// Returns the path of a backup of the file created now
+func backupPath(originalPath string) string {
+	dir := filepath.Dir(originalPath)
+	name := filepath.Base(originalPath)
+	return filepath.Join(dir, name+".backup."+time.Now().Format(time.RFC3339))
+}
+
+// A copy of files taken before modifying them, restoring it undoes the changes
+// Unlike Backup, the snapshot is kept in memory and nothing is written to disk
+type Snapshot struct {
+	files []snapshotFile
+	dirs  []string
+}
+
+// (internal)
+type snapshotFile struct {
+	path    string
+	exists  bool
+	content []byte
+	mode    os.FileMode
+	link    string
+}
+
+// TakeSnapshot captures the current state of the given files, missing files are removed on restore
+func TakeSnapshot(paths []string) (*Snapshot, error) {
+	snapshot := &Snapshot{}
+	seen := map[string]bool{}
+
+	for _, path := range paths {
+		stat, err := os.Lstat(path)
+
+		if os.IsNotExist(err) {
+			snapshot.files = append(snapshot.files, snapshotFile{path: path})
+
+			// folders created along the way are removed on restore as well
+			for dir := filepath.Dir(path); !seen[dir]; dir = filepath.Dir(dir) {
+				if _, err := os.Lstat(dir); err == nil {
+					break
+				}
+				seen[dir] = true
+				snapshot.dirs = append(snapshot.dirs, dir)
+			}
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		file := snapshotFile{path: path, exists: true, mode: stat.Mode()}
+
+		if stat.Mode()&os.ModeSymlink != 0 {
+			file.link, err = os.Readlink(path)
+		} else if stat.IsDir() {
+			err = errors.New("Cannot snapshot a folder: " + path)
+		} else {
+			file.content, err = os.ReadFile(path)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		snapshot.files = append(snapshot.files, file)
+	}
+
+	return snapshot, nil
+}
+
+// Restore puts the files back in the state they were in when the snapshot was taken
+func (snapshot *Snapshot) Restore() error {
+	for i := len(snapshot.files) - 1; i >= 0; i-- {
+		file := snapshot.files[i]
+
+		if err := os.RemoveAll(file.path); err != nil {
+			return err
+		}
+
+		if !file.exists {
+			continue
+		}
+
+		if err := os.MkdirAll(filepath.Dir(file.path), 0755); err != nil {
+			return err
+		}
+
+		if len(file.link) > 0 {
+			if err := os.Symlink(file.link, file.path); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := os.WriteFile(file.path, file.content, file.mode.Perm()); err != nil {
+			return err
+		}
+	}
+
+	// the folders did not exist, everything they contain was created since
+	for _, dir := range snapshot.dirs {
+		if err := os.RemoveAll(dir); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -59,3 +59,31 @@ func TestBackup(t *testing.T) {
 		assert.EqualError(t, err, expectedErr)
 	})
 }
+
+func TestSnapshot(t *testing.T) {
+	tempDir := t.TempDir()
+	existing := filepath.Join(tempDir, "existing.txt")
+	created := filepath.Join(tempDir, "new", "nested", "created.txt")
+
+	assert.NoError(t, os.WriteFile(existing, []byte("before"), 0600))
+
+	snapshot, err := TakeSnapshot([]string{existing, created})
+	assert.NoError(t, err)
+
+	assert.NoError(t, os.WriteFile(existing, []byte("after"), 0644))
+	assert.NoError(t, os.MkdirAll(filepath.Dir(created), 0755))
+	assert.NoError(t, os.WriteFile(created, []byte("created"), 0644))
+
+	assert.NoError(t, snapshot.Restore())
+
+	content, err := os.ReadFile(existing)
+	assert.NoError(t, err)
+	assert.Equal(t, "before", string(content))
+
+	stat, err := os.Stat(existing)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), stat.Mode().Perm())
+
+	_, err = os.Stat(filepath.Join(tempDir, "new"))
+	assert.True(t, os.IsNotExist(err), "created folders should be removed")
+}
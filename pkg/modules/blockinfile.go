@@ -2,11 +2,13 @@ package modules
 
 import (
 	"errors"
+	"fmt"
 	"io"
 	"os"
 	"regexp"
 	"strings"
 
+	"github.com/patrixr/glue/pkg/blueprint"
 	"github.com/patrixr/glue/pkg/core"
 	. "github.com/patrixr/glue/pkg/runtime"
 	"github.com/patrixr/q"
@@ -65,31 +67,119 @@ func init() {
 				NewField("backup?", BOOL, "the multi-line text block to be inserted or updated"),
 				NewField("create?", BOOL, "the multi-line text block to be inserted or updated"),
 			}), "the configuration for the block insertion").
-			Do(func(R Runtime, args *Arguments) (RTValue, error) {
-				data := args.EnsureDict(0).Map()
-				props, err := DecodeMap[BlockOpts](data)
+			Validate(func(R Runtime, args *Arguments) error {
+				props, err := DecodeMap[BlockOpts](args.EnsureDict(0).Map())
+
+				if err != nil {
+					return err
+				}
+
+				return props.Validate()
+			}).
+			Script(func(R Runtime, args *Arguments) (string, error) {
+				props, err := DecodeMap[BlockOpts](args.EnsureDict(0).Map())
+
+				if err != nil {
+					return "", err
+				}
+
+				path, err := scriptPath(glue, args, props.Path)
+
+				if err != nil {
+					return "", err
+				}
+
+				return BlockInFileScript(path, props), nil
+			}).
+			Resources(func(R Runtime, args *Arguments) ([]blueprint.Resource, error) {
+				props, err := decodeBlockOpts(glue, args)
 
 				if err != nil {
 					return nil, err
 				}
 
-				path, err := glue.SmartPath(props.Path)
+				// blocks with different markers can share a file
+				begin, _ := props.markers()
+
+				return []blueprint.Resource{{Kind: blueprint.ResourcePath, Name: props.Path, Part: begin}}, nil
+			}).
+			Check(func(R Runtime, args *Arguments) (RTValue, error) {
+				props, err := decodeBlockOpts(glue, args)
+
+				if err != nil {
+					return nil, err
+				}
+
+				changes, err := BlockInFileChanges(props)
 
 				if err != nil {
 					return nil, err
 				}
 
-				glue.Log.Info("[Blockinfile]", "path", props.Path)
+				recordChanges(glue.Scope(args), changes)
+
+				return nil, nil
+			}).
+			Do(func(R Runtime, args *Arguments) (RTValue, error) {
+				props, err := decodeBlockOpts(glue, args)
+
+				if err != nil {
+					return nil, err
+				}
 
-				props.Path = path
+				changes, err := BlockInFileChanges(props)
 
-				return nil, BlockInFile(props)
+				if err != nil || len(changes) == 0 {
+					return nil, err
+				}
+
+				scope := glue.Scope(args)
+				fmt.Fprintf(scope.Stdout, "[Blockinfile] path=%s\n", props.Path)
+
+				// the backup is named once, so that undoing the action removes it
+				backup := backupPath(props.Path)
+				paths := []string{props.Path}
+
+				if props.Backup {
+					paths = append(paths, backup)
+				}
+
+				snapshot, err := TakeSnapshot(paths)
+
+				if err != nil {
+					return nil, err
+				}
+
+				scope.OnUndo(snapshot.Restore)
+				recordChanges(scope, changes)
+
+				return nil, blockInFile(props, backup)
 			})
 
 		return nil
 	})
 }
 
+// (internal)
+// Decodes the module arguments and resolves the path of the file
+func decodeBlockOpts(glue *core.Glue, args *Arguments) (BlockOpts, error) {
+	props, err := DecodeMap[BlockOpts](args.EnsureDict(0).Map())
+
+	if err != nil {
+		return props, err
+	}
+
+	path, err := glue.ScopedPath(args, props.Path)
+
+	if err != nil {
+		return props, err
+	}
+
+	props.Path = path
+
+	return props, nil
+}
+
 const defaultMarker = "# {mark}"
 const defaultMarkerBegin = "BEGIN GLUE MANAGED BLOCK"
 const defaultMarkerEnd = "END GLUE MANAGED BLOCK"
@@ -107,13 +197,31 @@ type BlockOpts struct {
 	Path         string `json:"path"`
 }
 
+// Validate reports a missing path or block, before any file is modified
+func (props BlockOpts) Validate() error {
+	if len(props.Path) == 0 {
+		return errors.New("Missing path")
+	}
+
+	if props.State && len(props.Block) == 0 {
+		return errors.New("Cannot insert empty block")
+	}
+
+	return nil
+}
+
+// (internal)
+// Returns the lines surrounding the block
+func (opts BlockOpts) markers() (string, string) {
+	marker := stringOr(opts.Marker, defaultMarker)
+	beginLine := strings.Replace(marker, "{mark}", stringOr(opts.Markerbegin, defaultMarkerBegin), 1)
+	endLine := strings.Replace(marker, "{mark}", stringOr(opts.Markerend, defaultMarkerEnd), 1)
+	return beginLine, endLine
+}
+
 func BlockInString(text string, opts BlockOpts) string {
 	eol := "\n"
-	markerBegin := stringOr(opts.Markerbegin, defaultMarkerBegin)
-	markerEnd := stringOr(opts.Markerend, defaultMarkerEnd)
-	marker := stringOr(opts.Marker, defaultMarker)
-	beginLine := strings.Replace(marker, "{mark}", markerBegin, 1)
-	endLine := strings.Replace(marker, "{mark}", markerEnd, 1)
+	beginLine, endLine := opts.markers()
 	lines := strings.Split(text, eol)
 	found, _, beginIdx := q.Find(lines, q.Eq(beginLine))
 	foundEnd, _, endIdx := q.Find(lines, q.Eq(endLine))
@@ -176,7 +284,90 @@ func BlockInString(text string, opts BlockOpts) string {
 	return regexp.MustCompile("\n*$").ReplaceAllString(result, eol)
 }
 
+// (internal)
+// Edits the file given twice as argument: the first pass locates the markers and patterns, the second one prints the result
+const blockInFileAwk = `awk '
+function emit(  line) {
+  print ENVIRON["GLUE_BEGIN"]
+  while ((getline line < ENVIRON["GLUE_BLOCK"]) > 0) print line
+  print ENVIRON["GLUE_END"]
+  done = 1
+  insert = 0
+}
+NR == FNR {
+  if (!b && $0 == ENVIRON["GLUE_BEGIN"]) b = FNR
+  if (!e && $0 == ENVIRON["GLUE_END"]) e = FNR
+  if (!a && ENVIRON["GLUE_AFTER"] != "" && $0 ~ ENVIRON["GLUE_AFTER"]) a = FNR
+  if (!p && ENVIRON["GLUE_BEFORE"] != "" && $0 ~ ENVIRON["GLUE_BEFORE"]) p = FNR
+  next
+}
+FNR == 1 { insert = ENVIRON["GLUE_STATE"] && !(b && e) }
+b && e && FNR == b { if (ENVIRON["GLUE_STATE"]) emit(); skip = 1 }
+skip { if (FNR == e) skip = 0; next }
+insert && !a && FNR == p { emit() }
+{ print }
+insert && FNR == a { emit() }
+END { if (ENVIRON["GLUE_STATE"] && !(b && e) && !done) emit() }
+'`
+
+// BlockInFileScript returns the shell commands updating the block of a file, given as a shell word
+// The block is embedded as a heredoc, the patterns of insertafter and insertbefore are matched by awk
+func BlockInFileScript(path string, opts BlockOpts) string {
+	beginLine, endLine := opts.markers()
+	state := ""
+
+	commands := []string{"glue_file=" + path}
+
+	if opts.Create {
+		commands = append(commands, `[ -f "$glue_file" ] || touch "$glue_file"`)
+	} else {
+		commands = append(commands, `[ -f "$glue_file" ] || { echo "glue: $glue_file does not exist" >&2; exit 1; }`)
+	}
+
+	if opts.State {
+		state = "1"
+		commands = append(commands, "glue_block=$(mktemp)", blueprint.Heredoc(`cat > "$glue_block"`, opts.Block))
+	} else {
+		commands = append(commands, "glue_block=/dev/null")
+	}
+
+	commands = append(commands,
+		"glue_updated=$(mktemp)",
+		fmt.Sprintf(`GLUE_BLOCK="$glue_block" GLUE_BEGIN=%s GLUE_END=%s GLUE_AFTER=%s GLUE_BEFORE=%s GLUE_STATE=%s %s "$glue_file" "$glue_file" > "$glue_updated"`,
+			blueprint.ShellQuote(beginLine),
+			blueprint.ShellQuote(endLine),
+			blueprint.ShellQuote(opts.Insertafter),
+			blueprint.ShellQuote(opts.Insertbefore),
+			blueprint.ShellQuote(state),
+			blockInFileAwk,
+		),
+		`if ! cmp -s "$glue_file" "$glue_updated"; then`,
+	)
+
+	if opts.Backup {
+		commands = append(commands, `  cp -p "$glue_file" "$glue_file.backup.$(date +%Y-%m-%dT%H:%M:%S%z)"`)
+	}
+
+	commands = append(commands,
+		`  cat "$glue_updated" > "$glue_file"`,
+		"fi",
+		`rm -f "$glue_updated"`,
+	)
+
+	if opts.State {
+		commands = append(commands, `rm -f "$glue_block"`)
+	}
+
+	return strings.Join(commands, "\n")
+}
+
 func BlockInFile(props BlockOpts) error {
+	return blockInFile(props, backupPath(props.Path))
+}
+
+// (internal)
+// Writes the block to the file, saving its previous content to the backup path if requested
+func blockInFile(props BlockOpts, backup string) error {
 	path := props.Path
 	stat, err := os.Stat(path)
 	mode := os.FileMode(0644)
@@ -218,7 +409,7 @@ func BlockInFile(props BlockOpts) error {
 	updated := BlockInString(source, props)
 
 	if props.Backup && updated != source {
-		Backup(props.Path)
+		backupTo(props.Path, backup)
 	}
 
 	file.Truncate(0)
@@ -233,6 +424,39 @@ func BlockInFile(props BlockOpts) error {
 	return nil
 }
 
+// BlockInFileChanges lists the files BlockInFile would modify, without touching them
+func BlockInFileChanges(props BlockOpts) ([]FileChange, error) {
+	if props.State && len(props.Block) == 0 {
+		return nil, errors.New("Cannot insert empty block")
+	}
+
+	exists := true
+	data, err := os.ReadFile(props.Path)
+
+	if err != nil {
+		if !os.IsNotExist(err) || !props.Create {
+			return nil, err
+		}
+		// the file would be created
+		exists = false
+	}
+
+	source := string(data)
+	updated := BlockInString(source, props)
+
+	if exists && updated == source {
+		return nil, nil
+	}
+
+	changes := []FileChange{{Path: props.Path, Before: source, After: updated}}
+
+	if exists && props.Backup {
+		changes = append(changes, FileChange{Path: backupPath(props.Path), After: source})
+	}
+
+	return changes, nil
+}
+
 func stringOr(txt string, fallback string) string {
 	if len(txt) > 0 {
 		return txt
@@ -2,10 +2,13 @@ package modules_test
 
 import (
 	"os"
+	"os/exec"
 	"path/filepath"
 	"strings"
 	"testing"
 
+	"github.com/patrixr/glue/pkg/blueprint"
+	"github.com/patrixr/glue/pkg/core"
 	"github.com/patrixr/glue/pkg/modules"
 	"github.com/patrixr/q"
 	"github.com/stretchr/testify/assert"
@@ -332,3 +335,114 @@ func TestBlockInString(t *testing.T) {
 		assert.Error(t, err)
 	})
 }
+
+func TestBlockInFileChanges(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "file.txt")
+
+	assert.NoError(t, os.WriteFile(path, []byte("hello\n"), 0644))
+
+	props := modules.BlockOpts{Path: path, Block: "managed", State: true, Backup: true}
+
+	changes, err := modules.BlockInFileChanges(props)
+	assert.NoError(t, err)
+	assert.Len(t, changes, 2, "the file and its backup should change")
+	assert.Equal(t, path, changes[0].Path)
+	assert.Equal(t, "hello\n", changes[0].Before)
+	assert.Contains(t, changes[0].After, "managed")
+	assert.True(t, strings.HasPrefix(changes[1].Path, path+".backup."))
+
+	content, _ := os.ReadFile(path)
+	assert.Equal(t, "hello\n", string(content), "computing changes should not modify the file")
+
+	props.Backup = false
+	assert.NoError(t, modules.BlockInFile(props))
+
+	changes, err = modules.BlockInFileChanges(props)
+	assert.NoError(t, err)
+	assert.Empty(t, changes)
+
+	_, err = modules.BlockInFileChanges(modules.BlockOpts{Path: filepath.Join(dir, "missing"), Block: "x", State: true})
+	assert.Error(t, err)
+
+	changes, err = modules.BlockInFileChanges(modules.BlockOpts{Path: filepath.Join(dir, "missing"), Block: "x", State: true, Create: true})
+	assert.NoError(t, err)
+	assert.Len(t, changes, 1)
+}
+
+func TestBlockInFileRollback(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "file.txt")
+	script := filepath.Join(dir, "glue.lua")
+
+	assert.NoError(t, os.WriteFile(path, []byte("hello\n"), 0644))
+	assert.NoError(t, os.WriteFile(script, []byte(`
+		group("configs", function()
+			Blockinfile({ path = "`+path+`", block = "managed", state = true, backup = true })
+			Sh("false")
+		end)
+	`), 0644))
+
+	glue := core.NewGlue()
+	defer glue.Close()
+
+	assert.NoError(t, modules.Registry.InstallModules(glue))
+
+	plan, err := glue.CompilePlan(script)
+	assert.NoError(t, err)
+
+	results := plan.Execute(glue.Context)
+	blueprint.Rollback(&results, blueprint.RollbackGroup)
+
+	content, _ := os.ReadFile(path)
+	assert.Equal(t, "hello\n", string(content))
+
+	files, _ := filepath.Glob(path + ".backup.*")
+	assert.Empty(t, files, "the backup created by the action is removed")
+}
+
+func TestBlockInFileScript(t *testing.T) {
+	testCases := []struct {
+		name  string
+		input string
+		props modules.BlockOpts
+	}{
+		{
+			name:  "Block is inserted after the pattern",
+			input: "line 1\n# here\nline 2\n",
+			props: modules.BlockOpts{Block: "it's a block", Insertafter: "^# here", State: true},
+		},
+		{
+			name:  "Block is inserted before the pattern",
+			input: "line 1\n# here\nline 2\n",
+			props: modules.BlockOpts{Block: "a block", Insertbefore: "here$", State: true},
+		},
+		{
+			name:  "Existing block is replaced",
+			input: "line 1\n# BEGIN GLUE MANAGED BLOCK\nold\n# END GLUE MANAGED BLOCK\nline 2\n",
+			props: modules.BlockOpts{Block: "new\nblock", Insertafter: "^line 1", State: true},
+		},
+		{
+			name:  "Existing block is removed",
+			input: "line 1\n# BEGIN GLUE MANAGED BLOCK\nold\n# END GLUE MANAGED BLOCK\nline 2\n",
+			props: modules.BlockOpts{State: false},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "file")
+			assert.NoError(t, os.WriteFile(path, []byte(tc.input), 0644))
+
+			script := modules.BlockInFileScript(blueprint.ShellQuote(path), tc.props)
+			assert.NoError(t, exec.Command("sh", "-ec", script).Run())
+
+			content, err := os.ReadFile(path)
+			assert.NoError(t, err)
+
+			// the script keeps every line terminated, unlike BlockInString which may add blank lines
+			expected := strings.Trim(modules.BlockInString(tc.input, tc.props), "\n")
+			assert.Equal(t, expected, strings.Trim(string(content), "\n"))
+		})
+	}
+}
@@ -0,0 +1,24 @@
+package modules
+
+import (
+	"github.com/patrixr/glue/pkg/core"
+)
+
+// FileChange describes how a module would modify a file
+type FileChange struct {
+	Path   string
+	Before string
+	After  string
+}
+
+// (internal)
+// Marks the action as changed if any file is modified, and records the diff of each of them
+func recordChanges(scope *core.ActionScope, changes []FileChange) {
+	if len(changes) > 0 {
+		scope.Changed()
+	}
+
+	for _, change := range changes {
+		scope.Diff(change.Path, change.Before, change.After)
+	}
+}
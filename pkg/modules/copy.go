@@ -1,11 +1,16 @@
 package modules
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 
 	cp "github.com/otiai10/copy"
+	"github.com/patrixr/glue/pkg/blueprint"
 	"github.com/patrixr/glue/pkg/core"
 	. "github.com/patrixr/glue/pkg/runtime"
 )
@@ -43,29 +48,91 @@ func init() {
 				NewField("strategy?", STRING, "a strategy for how to manage conflicts (replace or merge, defaults to merge)"),
 				NewField("symlink?", STRING, "how to handle symlinks (deep/shallow/skip or the default skip)"),
 			}), "the copy options").
-			Do(func(R Runtime, args *Arguments) (RTValue, error) {
+			Validate(func(R Runtime, args *Arguments) error {
 				opts, err := DecodeMap[CopyOpts](args.EnsureDict(0).Map())
 
+				if err != nil {
+					return err
+				}
+
+				return opts.Validate()
+			}).
+			Script(func(R Runtime, args *Arguments) (string, error) {
+				opts, err := DecodeMap[CopyOpts](args.EnsureDict(0).Map())
+
+				if err != nil {
+					return "", err
+				}
+
+				src, err := glue.ScopedPath(args, opts.Source)
+
+				if err != nil {
+					return "", err
+				}
+
+				dest, err := scriptPath(glue, args, opts.Dest)
+
+				if err != nil {
+					return "", err
+				}
+
+				return CopyScript(src, dest, opts)
+			}).
+			Resources(func(R Runtime, args *Arguments) ([]blueprint.Resource, error) {
+				opts, err := decodeCopyOpts(glue, args)
+
 				if err != nil {
 					return nil, err
 				}
 
-				dest, err := glue.SmartPath(opts.Dest)
+				return []blueprint.Resource{{Kind: blueprint.ResourcePath, Name: opts.Dest}}, nil
+			}).
+			Check(func(R Runtime, args *Arguments) (RTValue, error) {
+				opts, err := decodeCopyOpts(glue, args)
 
 				if err != nil {
 					return nil, err
 				}
 
-				src, err := glue.SmartPath(opts.Source)
+				changes, err := CopyChanges(opts)
 
 				if err != nil {
 					return nil, err
 				}
 
-				glue.Log.Info("[Copy]", "src", opts.Source, "dst", opts.Dest)
+				recordChanges(glue.Scope(args), changes)
+
+				return nil, nil
+			}).
+			Do(func(R Runtime, args *Arguments) (RTValue, error) {
+				opts, err := decodeCopyOpts(glue, args)
+
+				if err != nil {
+					return nil, err
+				}
+
+				changes, err := CopyChanges(opts)
+
+				if err != nil || len(changes) == 0 {
+					return nil, err
+				}
+
+				scope := glue.Scope(args)
+				fmt.Fprintf(scope.Stdout, "[Copy] src=%s dst=%s\n", opts.Source, opts.Dest)
+
+				paths := make([]string, len(changes))
+				for i, change := range changes {
+					paths[i] = change.Path
+				}
+
+				snapshot, err := TakeSnapshot(paths)
+
+				if err != nil {
+					return nil, err
+				}
 
-				opts.Dest = dest
-				opts.Source = src
+				scope.OnUndo(snapshot.Restore)
+				recordChanges(scope, changes)
 
 				return nil, Copy(opts)
 			})
@@ -74,6 +141,33 @@ func init() {
 	})
 }
 
+// (internal)
+// Decodes the module arguments and resolves the source and destination paths
+func decodeCopyOpts(glue *core.Glue, args *Arguments) (CopyOpts, error) {
+	opts, err := DecodeMap[CopyOpts](args.EnsureDict(0).Map())
+
+	if err != nil {
+		return opts, err
+	}
+
+	dest, err := glue.ScopedPath(args, opts.Dest)
+
+	if err != nil {
+		return opts, err
+	}
+
+	src, err := glue.ScopedPath(args, opts.Source)
+
+	if err != nil {
+		return opts, err
+	}
+
+	opts.Dest = dest
+	opts.Source = src
+
+	return opts, nil
+}
+
 const (
 	StrategyMerge   = "merge"
 	StrategyReplace = "replace"
@@ -90,6 +184,104 @@ type CopyOpts struct {
 	Dest          string `json:"dest"`
 }
 
+// Validate reports missing paths and unknown strategies, before anything is copied
+func (opts CopyOpts) Validate() error {
+	switch {
+	case len(opts.Source) == 0:
+		return errors.New("Missing source")
+	case len(opts.Dest) == 0:
+		return errors.New("Missing dest")
+	case !slices.Contains([]string{"", StrategyMerge, StrategyReplace}, opts.Strategy):
+		return fmt.Errorf("Invalid strategy '%s'. Expected merge or replace", opts.Strategy)
+	case !slices.Contains([]string{"", SymlinkDeep, SymlinkShallow, SymlinkSkip}, opts.Symlink):
+		return fmt.Errorf("Invalid symlink option '%s'. Expected deep, shallow or skip", opts.Symlink)
+	}
+
+	return nil
+}
+
+// CopyScript returns the shell commands copying the source to the destination, a shell word
+// The content of the files is embedded in the commands, which don't need the source to exist
+func CopyScript(source string, dest string, opts CopyOpts) (string, error) {
+	stat, err := os.Stat(source)
+
+	if err != nil {
+		return "", err
+	}
+
+	commands := []string{}
+
+	if !stat.IsDir() {
+		commands = append(commands, fmt.Sprintf("mkdir -p \"$(dirname %s)\"", dest))
+	} else if opts.Strategy == StrategyReplace {
+		commands = append(commands, "rm -rf "+dest)
+	}
+
+	commands, err = copyCommands(commands, source, dest, opts.Symlink)
+
+	return strings.Join(commands, "\n"), err
+}
+
+// (internal)
+func copyCommands(commands []string, src string, dest string, symlink string) ([]string, error) {
+	info, err := os.Lstat(src)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if info.Mode()&os.ModeSymlink != 0 {
+		switch symlink {
+		case SymlinkShallow:
+			target, err := os.Readlink(src)
+
+			if err != nil {
+				return nil, err
+			}
+
+			return append(commands, fmt.Sprintf("ln -sfn %s %s", blueprint.ShellQuote(target), dest)), nil
+		case SymlinkDeep:
+			target, err := filepath.EvalSymlinks(src)
+
+			if err != nil {
+				return nil, err
+			}
+
+			return copyCommands(commands, target, dest, symlink)
+		}
+
+		return commands, nil
+	}
+
+	if !info.IsDir() {
+		content, err := os.ReadFile(src)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return append(commands, scriptPayload(dest, content), fmt.Sprintf("chmod %o %s", info.Mode().Perm(), dest)), nil
+	}
+
+	entries, err := os.ReadDir(src)
+
+	if err != nil {
+		return nil, err
+	}
+
+	commands = append(commands, "mkdir -p "+dest)
+
+	for _, entry := range entries {
+		commands, err = copyCommands(commands, filepath.Join(src, entry.Name()), dest+"/"+blueprint.ShellQuote(entry.Name()), symlink)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return commands, nil
+}
+
 func Copy(opts CopyOpts) error {
 	src := opts.Source
 	dst := opts.Dest
@@ -121,3 +313,157 @@ func Copy(opts CopyOpts) error {
 		},
 	})
 }
+
+// CopyChanges lists the files Copy would modify in the destination, without touching them
+func CopyChanges(opts CopyOpts) ([]FileChange, error) {
+	if _, err := os.Stat(opts.Source); err != nil {
+		return nil, err
+	}
+
+	if len(opts.Dest) == 0 {
+		return nil, errors.New(fmt.Sprintf("Invalid copy destination %s", opts.Dest))
+	}
+
+	var changes []FileChange
+
+	err := diffTree(opts.Source, opts.Dest, opts, &changes)
+
+	return changes, err
+}
+
+// (internal)
+// Compares a source entry with its destination, recursing into folders
+func diffTree(src string, dst string, opts CopyOpts, changes *[]FileChange) error {
+	info, err := os.Lstat(src)
+
+	if err != nil {
+		return err
+	}
+
+	if info.Mode()&os.ModeSymlink != 0 {
+		switch opts.Symlink {
+		case SymlinkShallow:
+			target, err := os.Readlink(src)
+			if err != nil {
+				return err
+			}
+			if existing, err := os.Readlink(dst); err != nil || existing != target {
+				*changes = append(*changes, FileChange{Path: dst, Before: existing, After: target})
+			}
+			return nil
+		case SymlinkDeep:
+			if info, err = os.Stat(src); err != nil {
+				return err
+			}
+		default:
+			return nil
+		}
+	}
+
+	dstInfo, err := os.Stat(dst)
+	exists := err == nil
+
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	if !info.IsDir() {
+		if exists && dstInfo.IsDir() {
+			return errors.New(fmt.Sprintf("Cannot copy file %s over folder %s", src, dst))
+		}
+		return diffFile(src, dst, exists, changes)
+	}
+
+	if exists && !dstInfo.IsDir() {
+		// the file would be replaced by a folder
+		before, err := os.ReadFile(dst)
+		if err != nil {
+			return err
+		}
+		*changes = append(*changes, FileChange{Path: dst, Before: string(before)})
+		exists = false
+	}
+
+	entries, err := os.ReadDir(src)
+
+	if err != nil {
+		return err
+	}
+
+	if exists && opts.Strategy == StrategyReplace {
+		if err := diffRemoved(src, dst, changes); err != nil {
+			return err
+		}
+	}
+
+	for _, entry := range entries {
+		if err := diffTree(filepath.Join(src, entry.Name()), filepath.Join(dst, entry.Name()), opts, changes); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// (internal)
+// Compares the content of a source file with its destination
+func diffFile(src string, dst string, exists bool, changes *[]FileChange) error {
+	after, err := os.ReadFile(src)
+
+	if err != nil {
+		return err
+	}
+
+	var before []byte
+
+	if exists {
+		if before, err = os.ReadFile(dst); err != nil {
+			return err
+		}
+	}
+
+	if !exists || !bytes.Equal(before, after) {
+		*changes = append(*changes, FileChange{Path: dst, Before: string(before), After: string(after)})
+	}
+
+	return nil
+}
+
+// (internal)
+// Lists the files of the destination which the replace strategy would remove
+func diffRemoved(src string, dst string, changes *[]FileChange) error {
+	return filepath.WalkDir(dst, func(path string, entry os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(dst, path)
+
+		if err != nil {
+			return err
+		}
+
+		if _, err := os.Lstat(filepath.Join(src, rel)); err == nil {
+			return nil
+		}
+
+		if entry.IsDir() {
+			// the folder's files are listed as we walk through it
+			return nil
+		}
+
+		if entry.Type()&os.ModeSymlink != 0 {
+			*changes = append(*changes, FileChange{Path: path})
+			return nil
+		}
+
+		before, err := os.ReadFile(path)
+
+		if err != nil {
+			return err
+		}
+
+		*changes = append(*changes, FileChange{Path: path, Before: string(before)})
+		return nil
+	})
+}
@@ -2,10 +2,13 @@ package modules_test
 
 import (
 	"os"
+	"os/exec"
 	"path/filepath"
 	"strings"
 	"testing"
 
+	"github.com/patrixr/glue/pkg/blueprint"
+	"github.com/patrixr/glue/pkg/core"
 	"github.com/patrixr/glue/pkg/modules"
 	"github.com/stretchr/testify/assert"
 )
@@ -182,3 +185,148 @@ func TestCopy(t *testing.T) {
 		})
 	}
 }
+
+func TestCopyChanges(t *testing.T) {
+	dir := createTestDir(t, map[string]string{
+		"src/file1.txt":        "content1",
+		"src/subdir/file2.txt": "content2",
+	})
+
+	opts := modules.CopyOpts{
+		Source:   filepath.Join(dir, "src"),
+		Dest:     filepath.Join(dir, "dest"),
+		Strategy: modules.StrategyReplace,
+	}
+
+	changes, err := modules.CopyChanges(opts)
+	assert.NoError(t, err)
+	assert.Len(t, changes, 2, "missing destination should change")
+
+	assert.NoError(t, modules.Copy(opts))
+
+	changes, err = modules.CopyChanges(opts)
+	assert.NoError(t, err)
+	assert.Empty(t, changes, "identical destination should not change")
+
+	edited := filepath.Join(dir, "dest", "subdir", "file2.txt")
+	assert.NoError(t, os.WriteFile(edited, []byte("edited"), 0644))
+
+	changes, err = modules.CopyChanges(opts)
+	assert.NoError(t, err)
+	assert.Equal(t, []modules.FileChange{{Path: edited, Before: "edited", After: "content2"}}, changes, "modified file should change")
+
+	assert.NoError(t, modules.Copy(opts))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "dest", "extra.txt"), []byte("extra"), 0644))
+
+	changes, err = modules.CopyChanges(opts)
+	assert.NoError(t, err)
+	assert.Len(t, changes, 1, "extra files should be removed with the replace strategy")
+
+	opts.Strategy = modules.StrategyMerge
+
+	changes, err = modules.CopyChanges(opts)
+	assert.NoError(t, err)
+	assert.Empty(t, changes, "extra files are kept with the merge strategy")
+}
+
+func TestCopyFromScriptDir(t *testing.T) {
+	dir := createTestDir(t, map[string]string{
+		"configs/src/file.txt": "content",
+		"configs/glue.lua": `
+			group("configs", function()
+				Copy({ source = "./src", dest = "./dest" })
+			end)
+		`,
+	})
+
+	glue := core.NewGlue()
+	defer glue.Close()
+
+	assert.NoError(t, modules.Registry.InstallModules(glue))
+
+	plan, err := glue.CompilePlan(filepath.Join(dir, "configs/glue.lua"))
+	assert.NoError(t, err)
+
+	// the actions run once the script is done, relative paths still resolve from its directory
+	results := plan.Execute(glue.Context)
+	assert.True(t, results.Success)
+
+	content, err := os.ReadFile(filepath.Join(dir, "configs/dest/file.txt"))
+	assert.NoError(t, err)
+	assert.Equal(t, "content", string(content))
+}
+
+func TestCopyFromBundle(t *testing.T) {
+	dir := createTestDir(t, map[string]string{
+		"configs/src/file.txt": "content",
+		"configs/glue.lua": `
+			group("configs", function()
+				Copy({ source = "./src", dest = "./dest" })
+			end)
+		`,
+	})
+
+	bundle := filepath.Join(dir, "plan.json")
+
+	glue := core.NewGlue()
+	assert.NoError(t, modules.Registry.InstallModules(glue))
+
+	plan, err := glue.CompilePlan(filepath.Join(dir, "configs/glue.lua"))
+	assert.NoError(t, err)
+	glue.Close()
+
+	file, err := os.Create(bundle)
+	assert.NoError(t, err)
+	assert.NoError(t, blueprint.Save(plan, filepath.Join(dir, "configs/glue.lua"), file))
+	file.Close()
+
+	// the bundle is applied from another directory than the one of the script
+	cwd, _ := os.Getwd()
+	assert.NoError(t, os.Chdir(t.TempDir()))
+	t.Cleanup(func() { os.Chdir(cwd) })
+
+	glue = core.NewGlue()
+	defer glue.Close()
+
+	assert.NoError(t, modules.Registry.InstallModules(glue))
+
+	loaded, err := glue.LoadPlan(bundle)
+	assert.NoError(t, err)
+
+	results := loaded.Execute(glue.Context)
+	assert.True(t, results.Success)
+
+	content, err := os.ReadFile(filepath.Join(dir, "configs/dest/file.txt"))
+	assert.NoError(t, err)
+	assert.Equal(t, "content", string(content))
+}
+
+func TestCopyValidate(t *testing.T) {
+	assert.NoError(t, modules.CopyOpts{Source: "./a", Dest: "./b"}.Validate())
+	assert.EqualError(t, modules.CopyOpts{Source: "./a"}.Validate(), "Missing dest")
+	assert.EqualError(t, modules.CopyOpts{Source: "./a", Dest: "./b", Strategy: "mrege"}.Validate(), "Invalid strategy 'mrege'. Expected merge or replace")
+}
+
+func TestCopyScript(t *testing.T) {
+	tempDir := createTestDir(t, map[string]string{
+		"src/file1.txt":         "content1",
+		"src/subdir/it's.txt":   "content 'quoted'\n",
+		"src/link.txt":          "link:src/file1.txt",
+		"dest/subdir/stale.txt": "stale",
+	})
+
+	opts := modules.CopyOpts{Strategy: modules.StrategyReplace}
+	script, err := modules.CopyScript(filepath.Join(tempDir, "src"), blueprint.ShellQuote(filepath.Join(tempDir, "dest")), opts)
+	assert.NoError(t, err)
+
+	// the content is embedded, the source isn't needed anymore
+	assert.NoError(t, os.RemoveAll(filepath.Join(tempDir, "src")))
+	assert.NoError(t, exec.Command("sh", "-ec", script).Run())
+
+	content, err := os.ReadFile(filepath.Join(tempDir, "dest/subdir/it's.txt"))
+	assert.NoError(t, err)
+	assert.Equal(t, "content 'quoted'\n", string(content))
+
+	assert.NoFileExists(t, filepath.Join(tempDir, "dest/subdir/stale.txt"))
+	assert.NoFileExists(t, filepath.Join(tempDir, "dest/link.txt"))
+}
@@ -1,9 +1,14 @@
 package modules
 
 import (
+	"fmt"
+	"strings"
+
+	"github.com/patrixr/glue/pkg/blueprint"
 	"github.com/patrixr/glue/pkg/core"
 	. "github.com/patrixr/glue/pkg/machine"
 	. "github.com/patrixr/glue/pkg/runtime"
+	"github.com/patrixr/q"
 )
 
 func init() {
@@ -48,6 +53,10 @@ func init() {
 	// })
 	// ```
 	//
+	// Every `Homebrew` call of the plan is merged into a single `brew bundle`, which runs at the position of the first call.
+	// The report lists the packages each group contributed. Calls with action options (`when`, `retry`, `notify`, ...),
+	// or within conditional groups and handlers, keep running on their own.
+	//
 	// #### HomebrewUpgrade
 	//
 	// ```lua
@@ -59,18 +68,18 @@ func init() {
 
 func HomebrewMod(glue *core.Glue) error {
 	ensure := func(R Runtime, args *Arguments) (RTValue, error) {
-		if !glue.Verbose {
-			glue.Log.Quiet()
-		}
+		scope := glue.Scope(args)
 
-		defer glue.Log.Loud()
+		fmt.Fprintln(scope.Stdout, "Ensuring Homebrew is installed")
 
-		glue.Log.Info("Ensuring Homebrew is installed")
+		if !IsHomebrewInstalled(glue.Machine) {
+			scope.Changed()
+		}
 
-		if err := InstallHomebrew(glue.Machine, glue.Log.Stdout, glue.Log.Stderr); err != nil {
+		if err := InstallHomebrew(args.Context(), glue.Machine, scope.Stdout, scope.Stderr); err != nil {
 			return nil, err
 		}
-		return nil, UpdateHomebrew(glue.Machine, glue.Log.Stdout, glue.Log.Stderr)
+		return nil, UpdateHomebrew(args.Context(), glue.Machine, scope.Stdout, scope.Stderr)
 	}
 
 	mainHomebrew := func(R Runtime, args *Arguments) (RTValue, error) {
@@ -80,31 +89,144 @@ func HomebrewMod(glue *core.Glue) error {
 			return nil, err
 		}
 
-		if !glue.Verbose {
-			glue.Log.Quiet()
+		scope := glue.Scope(args)
+
+		missing, err := HomebrewBundleCheck(args.Context(), glue.Machine, params, scope.Stdout, scope.Stderr)
+
+		if err != nil || !missing {
+			return nil, err
 		}
 
-		defer glue.Log.Loud()
+		fmt.Fprintln(scope.Stdout, "Running Homebrew")
 
-		glue.Log.Info("Running Homebrew")
+		scope.Changed()
 
-		return nil, HomebrewBundle(glue.Machine, params, glue.Log.Stdout, glue.Log.Stderr)
+		return nil, HomebrewBundle(args.Context(), glue.Machine, params, scope.Stdout, scope.Stderr)
+	}
+
+	checkEnsure := func(R Runtime, args *Arguments) (RTValue, error) {
+		if !IsHomebrewInstalled(glue.Machine) {
+			glue.Scope(args).Changed()
+		}
+		return nil, nil
+	}
+
+	checkHomebrew := func(R Runtime, args *Arguments) (RTValue, error) {
+		params, err := DecodeDict[HomebrewParams](args.EnsureDict(0))
+
+		if err != nil {
+			return nil, err
+		}
+
+		scope := glue.Scope(args)
+
+		changed, err := HomebrewBundleCheck(args.Context(), glue.Machine, params, scope.Stdout, scope.Stderr)
+
+		if changed {
+			scope.Changed()
+		}
+
+		return nil, err
 	}
 
 	upgrade := func(R Runtime, args *Arguments) (RTValue, error) {
-		if !glue.Verbose {
-			glue.Log.Quiet()
+		scope := glue.Scope(args)
+
+		fmt.Fprintln(scope.Stdout, "Upgrading Homebrew packages")
+
+		scope.Changed()
+
+		return nil, HomebrewUpgrade(args.Context(), glue.Machine, scope.Stdout, scope.Stderr)
+	}
+
+	ensureScript := func(R Runtime, args *Arguments) (string, error) {
+		return strings.Join([]string{
+			"if ! command -v brew >/dev/null 2>&1; then",
+			"  " + HomebrewInstallCommand,
+			"fi",
+			"brew update",
+		}, "\n"), nil
+	}
+
+	// the Brewfile is embedded in the script and read by brew from its standard input
+	homebrewScript := func(R Runtime, args *Arguments) (string, error) {
+		params, err := DecodeDict[HomebrewParams](args.EnsureDict(0))
+
+		if err != nil {
+			return "", err
 		}
 
-		defer glue.Log.Loud()
+		return blueprint.Heredoc("brew bundle --file=- --no-lock", strings.TrimSuffix(params.Brewfile(), "\n")), nil
+	}
+
+	homebrewResources := func(R Runtime, args *Arguments) ([]blueprint.Resource, error) {
+		params, err := DecodeDict[HomebrewParams](args.EnsureDict(0))
+
+		if err != nil {
+			return nil, err
+		}
+
+		// taps only provide packages, they aren't managed on their own
+		resources := []blueprint.Resource{}
+
+		for _, name := range params.Packages {
+			resources = append(resources, blueprint.Resource{Kind: blueprint.ResourcePackage, Name: "brew:" + name})
+		}
+
+		for _, name := range params.Casks {
+			resources = append(resources, blueprint.Resource{Kind: blueprint.ResourcePackage, Name: "cask:" + name})
+		}
 
-		glue.Log.Info("Upgrading Homebrew packages")
+		for _, name := range params.Mas {
+			resources = append(resources, blueprint.Resource{Kind: blueprint.ResourcePackage, Name: "mas:" + name})
+		}
+
+		for _, name := range params.Whalebrews {
+			resources = append(resources, blueprint.Resource{Kind: blueprint.ResourcePackage, Name: "whalebrew:" + name})
+		}
+
+		return resources, nil
+	}
+
+	upgradeScript := func(R Runtime, args *Arguments) (string, error) {
+		return "brew upgrade", nil
+	}
+
+	// every Homebrew call of the plan is merged into a single bundle
+	coalesce := func(calls [][]any) ([]any, error) {
+		merged := HomebrewParams{}
+
+		for _, call := range calls {
+			params, err := DecodeNative[HomebrewParams](call[0])
+
+			if err != nil {
+				return nil, err
+			}
+
+			merged = merged.Merge(params)
+		}
+
+		dict := map[string]any{}
+
+		for key, rows := range map[string][]string{
+			"packages":   merged.Packages,
+			"casks":      merged.Casks,
+			"taps":       merged.Taps,
+			"mas":        merged.Mas,
+			"whalebrews": merged.Whalebrews,
+		} {
+			if len(rows) > 0 {
+				dict[key] = q.Map(rows, func(row string) any { return row })
+			}
+		}
 
-		return nil, HomebrewUpgrade(glue.Machine, glue.Log.Stdout, glue.Log.Stderr)
+		return []any{dict}, nil
 	}
 
 	glue.Plug("HomebrewInstall", core.MODULE).
 		Brief("Installs Homebrew if not already installed").
+		Check(checkEnsure).
+		Script(ensureScript).
 		Do(ensure)
 
 	StringArray := TypedArray(STRING)
@@ -118,10 +240,15 @@ func HomebrewMod(glue *core.Glue) error {
 			NewField("whalebrews?", StringArray, "the whalebrews install"),
 			NewField("casks?", StringArray, "the homebrew casks to install"),
 		}), "the packages to install").
+		Check(checkHomebrew).
+		Coalesce(coalesce).
+		Script(homebrewScript).
+		Resources(homebrewResources).
 		Do(mainHomebrew)
 
 	glue.Plug("HomebrewUpgrade", core.MODULE).
 		Brief("Upgrades all homebrew packages").
+		Script(upgradeScript).
 		Do(upgrade)
 
 	return nil
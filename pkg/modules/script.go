@@ -0,0 +1,46 @@
+package modules
+
+import (
+	"encoding/base64"
+	"strings"
+
+	"github.com/patrixr/glue/pkg/blueprint"
+	"github.com/patrixr/glue/pkg/core"
+	. "github.com/patrixr/glue/pkg/runtime"
+)
+
+// (internal)
+// Returns a path as a shell word, paths within the home directory are kept relative to $HOME
+func scriptPath(glue *core.Glue, args *Arguments, path string) (string, error) {
+	if path == "~" {
+		return `"$HOME"`, nil
+	}
+
+	if strings.HasPrefix(path, "~/") {
+		return `"$HOME"/` + blueprint.ShellQuote(path[2:]), nil
+	}
+
+	resolved, err := glue.ScopedPath(args, path)
+
+	if err != nil {
+		return "", err
+	}
+
+	return blueprint.ShellQuote(resolved), nil
+}
+
+// (internal)
+// Returns the commands writing the content to a file, embedded as base64
+func scriptPayload(dest string, content []byte) string {
+	encoded := base64.StdEncoding.EncodeToString(content)
+	rows := []string{}
+
+	for len(encoded) > 76 {
+		rows = append(rows, encoded[:76])
+		encoded = encoded[76:]
+	}
+
+	rows = append(rows, encoded)
+
+	return blueprint.Heredoc("base64 -d > "+dest, strings.Join(rows, "\n"))
+}
@@ -1,6 +1,8 @@
 package modules
 
 import (
+	"fmt"
+
 	"github.com/patrixr/glue/pkg/core"
 	. "github.com/patrixr/glue/pkg/runtime"
 )
@@ -18,24 +20,66 @@ func init() {
 	//
 	// ```lua
 	// Sh("ls -la")
+	// Sh({ cmd = "ls -la" })
+	// ```
+	//
+	// With the `register` option, the call returns a handle to the result of the command. Its `stdout`, `rc` and `changed`
+	// fields are resolved when the plan runs, and can be used as arguments of later calls or as their `when` condition:
+	//
+	// ```lua
+	// local dock = Sh({ cmd = "defaults write com.apple.dock autohide -bool true", register = true })
+	// Sh("killall Dock", { when = dock.changed })
+	//
+	// local prefix = Sh({ cmd = "brew --prefix", register = true })
+	// Blockinfile({ path = "~/.zshrc", block = prefix.stdout, state = true })
 	// ```
 	//
 	Registry.RegisterModule(func(glue *core.Glue) error {
 		glue.Plug("sh", core.MODULE).
 			Brief("Run a shell command").
-			Arg("cmd", STRING, "the shell command to run").
+			Arg("cmd", ANY, "the shell command to run, or a table with a cmd field").
+			Validate(func(R Runtime, args *Arguments) error {
+				_, err := shellCommand(R, args.Get(0))
+				return err
+			}).
+			Script(func(R Runtime, args *Arguments) (string, error) {
+				return shellCommand(R, args.Get(0))
+			}).
 			Do(func(R Runtime, args *Arguments) (RTValue, error) {
-				if !glue.Verbose {
-					glue.Log.Quiet()
-				}
+				scope := glue.Scope(args)
+				cmd, err := shellCommand(R, args.Get(0))
 
-				defer glue.Log.Loud()
+				if err != nil {
+					return nil, err
+				}
 
-				cmd := args.EnsureString(0).String()
+				// the effects of a command are unknown, assume it changed something
+				scope.Changed()
 
-				return nil, glue.Machine.Shell(cmd, glue.Log.Stdout, glue.Log.Stderr)
+				return nil, glue.Machine.Shell(args.Context(), cmd, scope.Stdout, scope.Stderr)
 			})
 
 		return nil
 	})
 }
+
+// (internal)
+func shellCommand(R Runtime, val RTValue) (string, error) {
+	native, err := R.ToNative(val)
+
+	if err != nil {
+		return "", err
+	}
+
+	if dict, ok := native.(map[string]any); ok {
+		native = dict["cmd"]
+	}
+
+	cmd, ok := native.(string)
+
+	if !ok || len(cmd) == 0 {
+		return "", fmt.Errorf("Sh expects a command, received %v", native)
+	}
+
+	return cmd, nil
+}
@@ -0,0 +1,73 @@
+package runner
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/patrixr/glue/pkg/blueprint"
+	"github.com/patrixr/glue/pkg/core"
+	"github.com/patrixr/glue/pkg/docs"
+)
+
+type ApplyOptions struct {
+	Verbose  bool
+	File     string
+	Rollback string
+	FailFast bool
+	Step     string
+	Jobs     int
+	// Fail the plan when several groups manage the same resources
+	StrictOwnership bool
+}
+
+// RunGlueApply executes a blueprint bundle saved with `glue --plan --out`
+func RunGlueApply(opts ApplyOptions) {
+	glue := InitializeGlue(core.GlueOptions{
+		Verbose:         opts.Verbose,
+		FailFast:        opts.FailFast,
+		StrictOwnership: opts.StrictOwnership,
+		Jobs:            opts.Jobs,
+	})
+
+	defer glue.Close()
+
+	if !blueprint.ValidRollbackPolicy(opts.Rollback) {
+		glue.Log.Error(fmt.Sprintf("Invalid rollback policy '%s'. Expected group or run", opts.Rollback))
+		os.Exit(1)
+	}
+
+	plan, err := glue.LoadPlan(opts.File)
+
+	if err != nil {
+		glue.Log.Error(err)
+		os.Exit(1)
+	}
+
+	if len(opts.Step) > 0 {
+		if err := selectStep(plan, opts.Step); err != nil {
+			glue.Log.Error(err)
+			os.Exit(1)
+		}
+	}
+
+	if opts.Verbose {
+		fmt.Println(docs.PrintBlueprintDetails(plan))
+	}
+
+	stop := handleSignals(glue)
+	defer stop()
+
+	results := plan.Execute(glue.Context)
+
+	blueprint.Rollback(&results, blueprint.RollbackPolicy(opts.Rollback))
+
+	fmt.Println(docs.PrintResultReport(glue, results))
+
+	if interrupted(glue) {
+		os.Exit(interruptedExitCode)
+	}
+
+	if !results.Success {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,158 @@
+package runner
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/patrixr/glue/pkg/blueprint"
+	"github.com/patrixr/glue/pkg/core"
+)
+
+// (internal)
+// Asks the user to approve each action of the plan before it runs
+type prompter struct {
+	in     *bufio.Reader
+	out    io.Writer
+	total  int
+	all    bool
+	groups []string
+	// the remaining actions are skipped, the run itself ends normally
+	quit bool
+	// the steps skipped once the run was quit, which are still to run
+	abandoned []int
+	lock      sync.Mutex
+}
+
+// (internal)
+// The prompts are read line by line from in, which makes the session scriptable
+func newPrompter(plan blueprint.Blueprint, in io.Reader, out io.Writer) *prompter {
+	total := 0
+
+	blueprint.Walk(plan, func(node blueprint.Node) error {
+		if node.Action {
+			total++
+		}
+		return nil
+	})
+
+	return &prompter{
+		in:    bufio.NewReader(in),
+		out:   out,
+		total: total,
+	}
+}
+
+// (internal)
+// Implements blueprint.ApproveFunc. Actions of parallel groups are prompted one at a time
+func (p *prompter) approve(ctx context.Context, action blueprint.Node) (bool, string) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.quit {
+		return p.abandon(action)
+	}
+
+	if p.all {
+		return true, ""
+	}
+
+	for _, group := range p.groups {
+		if action.Group == group || strings.HasPrefix(action.Group, group+core.GroupSeparator) {
+			return false, fmt.Sprintf("Skipped interactively, along with the rest of %s", group)
+		}
+	}
+
+	p.describe(ctx, action)
+
+	for {
+		fmt.Fprint(p.out, "Run this action? [y]es, [n]o, [a]ll remaining, [s]kip group, [q]uit: ")
+
+		line, err := p.in.ReadString('\n')
+
+		if err != nil && len(line) == 0 {
+			// nothing left to read, e.g a script ran out of answers
+			fmt.Fprintln(p.out)
+			return p.abandon(action)
+		}
+
+		switch strings.ToLower(strings.TrimSpace(line)) {
+		case "y", "yes":
+			return true, ""
+		case "n", "no":
+			return false, "Skipped interactively"
+		case "a", "all":
+			p.all = true
+			return true, ""
+		case "s", "skip":
+			if len(action.Group) == 0 {
+				fmt.Fprintln(p.out, "The action doesn't belong to a group, use [n]o to skip it")
+				continue
+			}
+			p.groups = append(p.groups, action.Group)
+			return false, fmt.Sprintf("Skipped interactively, along with the rest of %s", action.Group)
+		case "q", "quit":
+			return p.abandon(action)
+		}
+
+		fmt.Fprintln(p.out, "Unknown answer")
+	}
+}
+
+// (internal)
+// Skips the action along with every remaining one
+func (p *prompter) abandon(action blueprint.Node) (bool, string) {
+	p.quit = true
+	p.abandoned = append(p.abandoned, action.Step)
+	return false, "Skipped, the run was quit"
+}
+
+// (internal)
+// Marks the steps skipped after quitting as never run, so that --resume and --rerun-failed run them
+func (p *prompter) markAbandoned(state *blueprint.RunState) {
+	for i, step := range state.Steps {
+		if slices.Contains(p.abandoned, step.Step) {
+			state.Steps[i].Cancelled = true
+		}
+	}
+}
+
+// (internal)
+// Prints the action, its arguments, its details and the diff of the files it would modify
+func (p *prompter) describe(ctx context.Context, action blueprint.Node) {
+	group := action.Group
+
+	if len(group) == 0 {
+		group = core.RootLevel
+	}
+
+	fmt.Fprintf(p.out, "\n[%d/%d] %s (%s) %s\n", action.Step, p.total, action.Name, group, action.ID)
+
+	if len(action.Args) > 0 {
+		args, err := json.MarshalIndent(action.Args, "  ", "  ")
+
+		if err == nil {
+			fmt.Fprintf(p.out, "  %s\n", args)
+		}
+	}
+
+	if len(action.Details) > 0 {
+		fmt.Fprintf(p.out, "  %s\n", action.Details)
+	}
+
+	// the condition of the action already holds, only its own check runs to get the diff of the files it would modify
+	node, ok := action.Blueprint.(*blueprint.SerialBlueprint)
+
+	if !ok || node.CheckFunction == nil {
+		return
+	}
+
+	if trace := node.CheckFunction(ctx); len(trace.Diff) > 0 {
+		fmt.Fprintln(p.out, strings.TrimRight(trace.Diff, "\n"))
+	}
+}
@@ -0,0 +1,79 @@
+package runner
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/patrixr/glue/pkg/blueprint"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Interactive(t *testing.T) {
+	ran := []string{}
+	record := func(name string) blueprint.ActionFunc {
+		return func(ctx context.Context, trace *blueprint.Trace) error {
+			ran = append(ran, name)
+			return nil
+		}
+	}
+
+	plan := blueprint.NewSerialBlueprint("root")
+
+	for _, name := range []string{"configs", "tools"} {
+		group := blueprint.NewSerialBlueprint(name)
+		group.Group = name
+		group.Action(blueprint.ActionDef{Name: name + "-a", Group: name, Fn: record(name + "-a")})
+		group.Action(blueprint.ActionDef{Name: name + "-b", Group: name, Fn: record(name + "-b")})
+		plan.Add(group)
+	}
+
+	conditions := 0
+	when := &blueprint.Condition{Eval: func(ctx context.Context) (bool, error) {
+		conditions++
+		return true, nil
+	}}
+
+	plan.Action(blueprint.ActionDef{Name: "last", When: when, Fn: record("last")})
+
+	blueprint.Resolve(plan, false)
+
+	var prompt *prompter
+
+	run := func(answers string) blueprint.Results {
+		ran = []string{}
+		conditions = 0
+
+		out := &strings.Builder{}
+		prompt = newPrompter(plan, strings.NewReader(answers), out)
+
+		return plan.Execute(blueprint.WithApproval(context.Background(), prompt.approve))
+	}
+
+	t.Run("skips the declined actions and groups", func(t *testing.T) {
+		results := run("n\ny\ns\nmaybe\ny\n")
+
+		assert.Equal(t, []string{"configs-b", "last"}, ran)
+		assert.Equal(t, 3, results.Count(blueprint.StatusSkipped))
+		assert.Equal(t, 1, conditions, "describing an action doesn't evaluate its condition again")
+	})
+
+	t.Run("runs everything once all is chosen", func(t *testing.T) {
+		run("y\na\n")
+		assert.Equal(t, []string{"configs-a", "configs-b", "tools-a", "tools-b", "last"}, ran)
+	})
+
+	t.Run("stops the run on quit", func(t *testing.T) {
+		results := run("y\nq\n")
+
+		assert.Equal(t, []string{"configs-a"}, ran)
+		assert.Equal(t, 4, results.Count(blueprint.StatusSkipped))
+		assert.True(t, results.Success, "quitting is not a failure")
+
+		state := blueprint.NewRunState("glue.lua", plan, results)
+		prompt.markAbandoned(&state)
+
+		assert.Equal(t, []int{2, 3, 4, 5}, state.FailedSteps(), "the steps skipped by quitting are resumed")
+		assert.Equal(t, blueprint.StatusSkipped, results.Traces[1].Status, "the report keeps them as skipped")
+	})
+}
@@ -0,0 +1,157 @@
+package runner
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/patrixr/glue/pkg/core"
+	"github.com/patrixr/glue/pkg/docs"
+)
+
+type PlanOptions struct {
+	Path      string
+	Format    string
+	Compare   string
+	Revisions []string
+}
+
+// RunGluePlan prints the compiled plan, or compares it to a saved plan or to the plan of other git revisions
+func RunGluePlan(opts PlanOptions) {
+	log := core.CreateLogger()
+
+	output, err := printPlan(opts)
+
+	if err != nil {
+		log.Error(err)
+		os.Exit(1)
+	}
+
+	fmt.Println(output)
+}
+
+// (internal)
+func printPlan(opts PlanOptions) (string, error) {
+	if len(opts.Compare) > 0 && len(opts.Revisions) > 0 {
+		return "", errors.New("--compare and --rev cannot be used together")
+	}
+
+	if len(opts.Revisions) > 2 {
+		return "", errors.New("At most two revisions can be compared")
+	}
+
+	var script string
+	var err error
+	if opts.Path != "" {
+		script, err = core.TryFindGlueFile(opts.Path)
+	} else {
+		script, err = core.AutoDetectScriptFile()
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	if len(opts.Compare) == 0 && len(opts.Revisions) == 0 {
+		glue := InitializeGlue(core.GlueOptions{})
+		defer glue.Close()
+
+		if opts.Format != "tree" {
+			glue.Log.Stdout.Loud = false
+		}
+
+		plan, err := glue.CompilePlan(script)
+
+		if err != nil {
+			return "", err
+		}
+
+		return docs.PrintPlan(plan, opts.Format)
+	}
+
+	var before, after docs.PlanDocument
+
+	switch {
+	case len(opts.Compare) > 0:
+		before, err = readPlanFile(opts.Compare)
+	default:
+		before, err = compileRevision(script, opts.Revisions[0])
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	if len(opts.Revisions) == 2 {
+		after, err = compileRevision(script, opts.Revisions[1])
+	} else {
+		after, err = compileDocument(script)
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	return docs.PrintComparison(docs.ComparePlans(before, after), opts.Format)
+}
+
+// (internal)
+func readPlanFile(file string) (docs.PlanDocument, error) {
+	reader, err := os.Open(file)
+
+	if err != nil {
+		return docs.PlanDocument{}, err
+	}
+
+	defer reader.Close()
+
+	doc, err := docs.ReadPlanDocument(reader)
+
+	if err != nil {
+		return doc, fmt.Errorf("Unable to read the plan %s: %w", file, err)
+	}
+
+	return doc, nil
+}
+
+// (internal)
+// Compiles the script with a fresh Glue instance, without logging the groups it declares
+func compileDocument(script string) (docs.PlanDocument, error) {
+	glue := InitializeGlue(core.GlueOptions{})
+	defer glue.Close()
+
+	glue.Log.Stdout.Loud = false
+
+	plan, err := glue.CompilePlan(script)
+
+	if err != nil {
+		return docs.PlanDocument{}, err
+	}
+
+	return docs.NewPlanDocument(plan), nil
+}
+
+// (internal)
+func compileRevision(script string, revision string) (docs.PlanDocument, error) {
+	dir, err := os.MkdirTemp("", "glue-plan-")
+
+	if err != nil {
+		return docs.PlanDocument{}, err
+	}
+
+	defer os.RemoveAll(dir)
+
+	extracted, err := checkoutRevision(script, revision, dir)
+
+	if err != nil {
+		return docs.PlanDocument{}, err
+	}
+
+	doc, err := compileDocument(extracted)
+
+	if err != nil {
+		return doc, fmt.Errorf("Unable to compile the plan at revision %s: %w", revision, err)
+	}
+
+	return doc, nil
+}
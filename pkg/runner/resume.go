@@ -0,0 +1,65 @@
+package runner
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/patrixr/glue/pkg/blueprint"
+	"github.com/patrixr/glue/pkg/core"
+)
+
+// (internal)
+// Skips the steps which completed during the last run of the plan, according to the options
+// Returns false when there is nothing left to run
+func skipCompletedSteps(glue *core.Glue, plan blueprint.Blueprint, script string, opts RunOptions) (bool, error) {
+	state, err := core.LoadRunState(script, plan)
+
+	if err != nil {
+		return false, err
+	}
+
+	if state == nil {
+		glue.Log.Warn("No previous run of this plan was found, running every step")
+		return true, nil
+	}
+
+	failed := state.FailedSteps()
+
+	if len(failed) == 0 {
+		glue.Log.Info(fmt.Sprintf("The previous run (%s) completed successfully, nothing to run", state.Time.Format("2006-01-02 15:04")))
+		return false, nil
+	}
+
+	if opts.RerunFailed {
+		blueprint.SkipSteps(plan, func(step int) bool {
+			return !slices.Contains(failed, step)
+		}, "Completed in the previous run")
+		return true, nil
+	}
+
+	from := state.ResumeStep()
+
+	glue.Log.Info(fmt.Sprintf("Resuming the previous run from step %d (%s)", from, state.StepID(from)))
+
+	blueprint.SkipSteps(plan, func(step int) bool {
+		return step < from
+	}, "Completed in the previous run")
+
+	return true, nil
+}
+
+// (internal)
+// Removes every action of the plan but the one with the given ID
+func selectStep(plan blueprint.Blueprint, id string) error {
+	step := blueprint.FindStep(plan, id)
+
+	if step == 0 {
+		return fmt.Errorf("Unknown step %s, the IDs of the actions are listed by --plan", id)
+	}
+
+	blueprint.KeepSteps(plan, func(s int) bool {
+		return s == step
+	})
+
+	return nil
+}
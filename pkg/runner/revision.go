@@ -0,0 +1,130 @@
+package runner
+
+import (
+	"archive/tar"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// (internal)
+// Extracts the git repository containing the script, as it was at the given revision, into dir
+// Returns the path of the script within the extracted copy
+func checkoutRevision(script string, revision string, dir string) (string, error) {
+	abs, err := filepath.Abs(script)
+
+	if err != nil {
+		return "", err
+	}
+
+	// git reports the top level with symlinks resolved
+	abs, err = filepath.EvalSymlinks(abs)
+
+	if err != nil {
+		return "", err
+	}
+
+	toplevel, err := git(filepath.Dir(abs), "rev-parse", "--show-toplevel")
+
+	if err != nil {
+		return "", fmt.Errorf("The scripts are not in a git repository: %w", err)
+	}
+
+	root := strings.TrimSpace(toplevel.String())
+	relative, err := filepath.Rel(root, abs)
+
+	if err != nil {
+		return "", err
+	}
+
+	archive, err := git(root, "archive", "--format=tar", revision)
+
+	if err != nil {
+		return "", fmt.Errorf("Unable to read revision %s: %w", revision, err)
+	}
+
+	if err := extractTar(archive, dir); err != nil {
+		return "", err
+	}
+
+	extracted := filepath.Join(dir, relative)
+
+	if _, err := os.Stat(extracted); err != nil {
+		return "", fmt.Errorf("%s does not exist at revision %s", relative, revision)
+	}
+
+	return extracted, nil
+}
+
+// (internal)
+func git(dir string, args ...string) (*bytes.Buffer, error) {
+	var stdout, stderr bytes.Buffer
+
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); len(msg) > 0 {
+			return nil, fmt.Errorf("%s", msg)
+		}
+		return nil, err
+	}
+
+	return &stdout, nil
+}
+
+// (internal)
+func extractTar(r io.Reader, dir string) error {
+	reader := tar.NewReader(r)
+
+	for {
+		header, err := reader.Next()
+
+		if err == io.EOF {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		target := filepath.Join(dir, header.Name)
+
+		if !strings.HasPrefix(target, filepath.Clean(dir)+string(os.PathSeparator)) {
+			return fmt.Errorf("Invalid path in archive: %s", header.Name)
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+		case tar.TypeSymlink:
+			if err := os.Symlink(header.Linkname, target); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+
+			file, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.FileMode(header.Mode))
+
+			if err != nil {
+				return err
+			}
+
+			_, err = io.Copy(file, reader)
+			file.Close()
+
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
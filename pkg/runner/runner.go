@@ -3,22 +3,43 @@ package runner
 import (
 	"fmt"
 	"os"
+	"strings"
 
+	"github.com/patrixr/glue/pkg/blueprint"
 	"github.com/patrixr/glue/pkg/core"
 	"github.com/patrixr/glue/pkg/docs"
 )
 
 type RunOptions struct {
-	Verbose  bool
-	PlanOnly bool
-	Path     string
-	Selector string
+	Verbose     bool
+	PlanOnly    bool
+	Format      string
+	Check       bool
+	Diff        bool
+	Interactive bool
+	Rollback    string
+	FailFast    bool
+	// Fail the plan when several groups manage the same resources
+	StrictOwnership bool
+	Resume          bool
+	RerunFailed     bool
+	Step            string
+	Path            string
+	Out             string
+	Jobs            int
+	NoDeps          bool
+	Selector        string
 }
 
 func RunGlue(opts RunOptions) {
 	glue := InitializeGlue(core.GlueOptions{
-		Selector: opts.Selector,
-		Verbose:  opts.Verbose,
+		Selector:        opts.Selector,
+		Verbose:         opts.Verbose,
+		Jobs:            opts.Jobs,
+		NoDeps:          opts.NoDeps,
+		Diff:            opts.Diff,
+		FailFast:        opts.FailFast,
+		StrictOwnership: opts.StrictOwnership,
 	})
 
 	defer glue.Close()
@@ -28,6 +49,31 @@ func RunGlue(opts RunOptions) {
 		os.Exit(1)
 	}
 
+	if opts.Resume && opts.RerunFailed {
+		glue.Log.Error("--resume and --rerun-failed cannot be used together")
+		os.Exit(1)
+	}
+
+	if len(opts.Step) > 0 && (opts.Resume || opts.RerunFailed) {
+		glue.Log.Error("--step cannot be used with --resume or --rerun-failed")
+		os.Exit(1)
+	}
+
+	if opts.Interactive && (opts.PlanOnly || opts.Check || opts.Diff) {
+		glue.Log.Error("--interactive cannot be used with --plan, --check or --diff")
+		os.Exit(1)
+	}
+
+	if !docs.ValidPlanFormat(opts.Format) {
+		glue.Log.Error(fmt.Sprintf("Unknown plan format '%s'. Expected one of %s", opts.Format, strings.Join(docs.PlanFormats, ", ")))
+		os.Exit(1)
+	}
+
+	if !blueprint.ValidRollbackPolicy(opts.Rollback) {
+		glue.Log.Error(fmt.Sprintf("Invalid rollback policy '%s'. Expected group or run", opts.Rollback))
+		os.Exit(1)
+	}
+
 	var script string
 	var err error
 	if opts.Path != "" {
@@ -41,6 +87,13 @@ func RunGlue(opts RunOptions) {
 		os.Exit(1)
 	}
 
+	// keeps the output of --plan parseable, errors and details are still reported on stderr
+	parseable := opts.PlanOnly && opts.Format != "tree"
+
+	if parseable {
+		glue.Log.Stdout.Loud = false
+	}
+
 	plan, err := glue.CompilePlan(script)
 
 	if err != nil {
@@ -48,22 +101,120 @@ func RunGlue(opts RunOptions) {
 		os.Exit(1)
 	}
 
-	if opts.Verbose {
+	if len(opts.Step) > 0 {
+		if err := selectStep(plan, opts.Step); err != nil {
+			glue.Log.Error(err)
+			os.Exit(1)
+		}
+	}
+
+	if opts.Verbose && parseable {
+		fmt.Fprintln(os.Stderr, docs.PrintBlueprintDetails(plan))
+	} else if opts.Verbose {
 		fmt.Println(docs.PrintBlueprintDetails(plan))
 	}
 
+	if len(opts.Out) > 0 {
+		if err := savePlan(plan, script, opts.Out); err != nil {
+			glue.Log.Error(err)
+			os.Exit(1)
+		}
+	}
+
 	if opts.PlanOnly {
-		fmt.Println(plan.PrettyPrint())
+		output, err := docs.PrintPlan(plan, opts.Format)
+
+		if err != nil {
+			glue.Log.Error(err)
+			os.Exit(1)
+		}
+
+		fmt.Println(output)
 		return
 	}
 
-	results := plan.Execute()
+	if opts.Resume || opts.RerunFailed {
+		proceed, err := skipCompletedSteps(glue, plan, script, opts)
+
+		if err != nil {
+			glue.Log.Error(err)
+			os.Exit(1)
+		}
+
+		if !proceed {
+			return
+		}
+	}
 
-	glue.Test()
+	stop := handleSignals(glue)
+	defer stop()
+
+	// diffs are a preview of the changes, nothing is applied
+	if opts.Check || opts.Diff {
+		results := plan.Check(glue.Context)
+
+		fmt.Println(docs.PrintCheckReport(results))
+
+		if interrupted(glue) {
+			os.Exit(interruptedExitCode)
+		}
+
+		if results.ErrorCount > 0 {
+			os.Exit(1)
+		}
+		return
+	}
+
+	var prompt *prompter
+
+	if opts.Interactive {
+		// the prompts show the changes each action would make to files
+		glue.Diff = true
+		prompt = newPrompter(plan, os.Stdin, os.Stdout)
+		glue.Context = blueprint.WithApproval(glue.Context, prompt.approve)
+	}
+
+	results := plan.Execute(glue.Context)
+
+	blueprint.Rollback(&results, blueprint.RollbackPolicy(opts.Rollback))
+
+	// a single step doesn't represent a run of the plan
+	if len(opts.Step) == 0 {
+		state := blueprint.NewRunState(script, plan, results)
+
+		if prompt != nil {
+			prompt.markAbandoned(&state)
+		}
+
+		if err := core.SaveRunState(state); err != nil {
+			glue.Log.Warn("Unable to save the state of the run", "error", err)
+		}
+	}
+
+	if !interrupted(glue) {
+		glue.Test()
+	}
 
 	fmt.Println(docs.PrintResultReport(glue, results))
 
+	if interrupted(glue) {
+		os.Exit(interruptedExitCode)
+	}
+
 	if !results.Success {
 		os.Exit(1)
 	}
 }
+
+// (internal)
+func savePlan(plan blueprint.Blueprint, script string, out string) error {
+	file, err := os.Create(out)
+
+	if err != nil {
+		return err
+	}
+
+	defer file.Close()
+
+	return blueprint.Save(plan, script, file)
+}
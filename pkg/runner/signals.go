@@ -0,0 +1,46 @@
+package runner
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/patrixr/glue/pkg/core"
+)
+
+// (internal)
+// Exit code of a run interrupted by a signal
+const interruptedExitCode = 130
+
+// (internal)
+// Cancels the context of glue on SIGINT or SIGTERM. The current action is interrupted,
+// the remaining ones are skipped and the report is still printed. A second signal exits immediately
+func handleSignals(glue *core.Glue) func() {
+	ctx, stop := signal.NotifyContext(glue.Context, os.Interrupt, syscall.SIGTERM)
+	done := make(chan struct{})
+
+	glue.Context = ctx
+
+	go func() {
+		<-ctx.Done()
+
+		select {
+		case <-done:
+			return
+		default:
+		}
+
+		glue.Log.Warn("Interrupted, stopping the run. Interrupt again to exit immediately")
+		stop()
+	}()
+
+	return func() {
+		close(done)
+		stop()
+	}
+}
+
+// (internal)
+func interrupted(glue *core.Glue) bool {
+	return glue.Context.Err() != nil
+}
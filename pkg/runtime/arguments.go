@@ -1,14 +1,30 @@
 package runtime
 
-import "fmt"
+import (
+	"context"
+	"fmt"
+)
 
 type Arguments struct {
 	R    Runtime
 	data []RTValue
+	ctx  context.Context
 }
 
 func NewArguments(R Runtime, data []RTValue) *Arguments {
-	return &Arguments{R, data}
+	return &Arguments{R, data, nil}
+}
+
+func NewArgumentsWithContext(ctx context.Context, R Runtime, data []RTValue) *Arguments {
+	return &Arguments{R, data, ctx}
+}
+
+// Context returns the context the arguments were passed in, defaults to context.Background()
+func (args *Arguments) Context() context.Context {
+	if args.ctx == nil {
+		return context.Background()
+	}
+	return args.ctx
 }
 
 func (args *Arguments) Len() int {
@@ -102,3 +118,8 @@ func (args *Arguments) Get(i int) RTValue {
 
 	return args.data[i]
 }
+
+// IsNil returns true if the argument was omitted or explicitly set to nil
+func (args *Arguments) IsNil(i int) bool {
+	return i >= len(args.data) || args.data[i] == nil || args.data[i].Type().Is(NIL)
+}
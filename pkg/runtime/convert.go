@@ -1,6 +1,10 @@
 package runtime
 
-import "github.com/mitchellh/mapstructure"
+import (
+	"strings"
+
+	"github.com/mitchellh/mapstructure"
+)
 
 func DecodeDict[T any](dict RTDict) (T, error) {
 	mp := dict.Map()
@@ -8,6 +12,11 @@ func DecodeDict[T any](dict RTDict) (T, error) {
 }
 
 func DecodeMap[T any](mp map[interface{}]interface{}) (T, error) {
+	return DecodeNative[T](mp)
+}
+
+// DecodeNative decodes native data, as returned by Runtime.ToNative, into a struct
+func DecodeNative[T any](value interface{}) (T, error) {
 	var data T
 
 	config := &mapstructure.DecoderConfig{
@@ -15,6 +24,7 @@ func DecodeMap[T any](mp map[interface{}]interface{}) (T, error) {
 		Result:           &data,
 		TagName:          "json",
 		ErrorUnused:      false,
+		MatchName:        matchName,
 	}
 
 	decoder, err := mapstructure.NewDecoder(config)
@@ -22,9 +32,18 @@ func DecodeMap[T any](mp map[interface{}]interface{}) (T, error) {
 		return data, err
 	}
 
-	if err = decoder.Decode(mp); err != nil {
+	if err = decoder.Decode(value); err != nil {
 		return data, err
 	}
 
 	return data, nil
 }
+
+// (internal)
+// Keys are matched regardless of case and underscores, e.g. "depends_on" matches "DependsOn"
+func matchName(mapKey string, fieldName string) bool {
+	return strings.EqualFold(
+		strings.ReplaceAll(mapKey, "_", ""),
+		strings.ReplaceAll(fieldName, "_", ""),
+	)
+}
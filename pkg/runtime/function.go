@@ -0,0 +1,13 @@
+package runtime
+
+import "errors"
+
+// NativeFunction holds a function of the runtime within native data (see Runtime.ToNative)
+// Functions only live as long as the runtime which created them, they cannot be serialized
+type NativeFunction struct {
+	Fn RTFunction
+}
+
+func (fn NativeFunction) MarshalJSON() ([]byte, error) {
+	return nil, errors.New("Functions cannot be serialized")
+}
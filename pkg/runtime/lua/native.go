@@ -0,0 +1,168 @@
+package lua
+
+import (
+	"fmt"
+
+	"github.com/patrixr/glue/pkg/runtime"
+	lua "github.com/yuin/gopher-lua"
+)
+
+// ToNative converts a runtime value into plain Go data (string, float64, bool, map, slice or nil)
+// The result can be converted back with FromNative, and serialized unless it contains functions
+// (kept as runtime.NativeFunction)
+func (luaruntime *LuaRuntime) ToNative(v runtime.RTValue) (interface{}, error) {
+	switch val := v.(type) {
+	case LuaStringVal:
+		return string(val.Raw()), nil
+	case LuaNumberVal:
+		return float64(val.Raw()), nil
+	case LuaBoolVal:
+		return bool(val.Raw()), nil
+	case LuaDictVal:
+		return luaToNative(val.Raw())
+	case LuaArrayVal:
+		return luaToNative(val.Raw())
+	case LuaFunctionVal:
+		return runtime.NativeFunction{Fn: val}, nil
+	case LuaNilVal:
+		return nil, nil
+	case LuaValue[lua.LValue]:
+		return luaToNative(val.Raw())
+	}
+
+	return nil, fmt.Errorf("Unable to convert value of type %s", runtime.TypeName(v.Type()))
+}
+
+// FromNative converts plain Go data back into a runtime value of the expected type
+func (luaruntime *LuaRuntime) FromNative(v interface{}, typ runtime.Type) (runtime.RTValue, error) {
+	raw, err := nativeToLua(luaruntime.L, v)
+
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case typ.Is(runtime.STRING):
+		if str, ok := raw.(lua.LString); ok {
+			return NewString(str), nil
+		}
+	case typ.Is(runtime.NUMBER):
+		if num, ok := raw.(lua.LNumber); ok {
+			return NewNumber(num), nil
+		}
+	case typ.Is(runtime.BOOL):
+		if b, ok := raw.(lua.LBool); ok {
+			return NewBool(b), nil
+		}
+	case typ.Is(runtime.DICT):
+		if tbl, ok := raw.(*lua.LTable); ok {
+			return NewDict(tbl), nil
+		}
+	case typ.Is(runtime.ARRAY):
+		if tbl, ok := raw.(*lua.LTable); ok {
+			return NewArray(tbl), nil
+		}
+	case typ.Is(runtime.NIL):
+		if raw == lua.LNil {
+			return Nil(), nil
+		}
+	case typ.Is(runtime.ANY):
+		return AnyValue(raw), nil
+	}
+
+	return nil, fmt.Errorf("Expected a %s, received %v instead", runtime.TypeName(typ), v)
+}
+
+// (internal)
+func luaToNative(lv lua.LValue) (interface{}, error) {
+	switch val := lv.(type) {
+	case *lua.LNilType:
+		return nil, nil
+	case lua.LBool:
+		return bool(val), nil
+	case lua.LString:
+		return string(val), nil
+	case lua.LNumber:
+		return float64(val), nil
+	case *lua.LFunction:
+		return runtime.NativeFunction{Fn: NewFunc(val)}, nil
+	case *lua.LTable:
+		maxn := val.MaxN()
+
+		if maxn > 0 {
+			list := make([]interface{}, 0, maxn)
+			for i := 1; i <= maxn; i++ {
+				item, err := luaToNative(val.RawGetInt(i))
+				if err != nil {
+					return nil, err
+				}
+				list = append(list, item)
+			}
+			return list, nil
+		}
+
+		dict := map[string]interface{}{}
+		var err error
+
+		val.ForEach(func(key lua.LValue, value lua.LValue) {
+			if err != nil {
+				return
+			}
+
+			item, e := luaToNative(value)
+
+			if e != nil {
+				err = e
+				return
+			}
+
+			dict[key.String()] = item
+		})
+
+		return dict, err
+	}
+
+	return nil, fmt.Errorf("Values of type %s cannot be converted to data", lv.Type().String())
+}
+
+// (internal)
+func nativeToLua(L *lua.LState, v interface{}) (lua.LValue, error) {
+	switch val := v.(type) {
+	case nil:
+		return lua.LNil, nil
+	case bool:
+		return lua.LBool(val), nil
+	case string:
+		return lua.LString(val), nil
+	case float64:
+		return lua.LNumber(val), nil
+	case int:
+		return lua.LNumber(val), nil
+	case []interface{}:
+		tbl := L.NewTable()
+		for _, item := range val {
+			lv, err := nativeToLua(L, item)
+			if err != nil {
+				return nil, err
+			}
+			tbl.Append(lv)
+		}
+		return tbl, nil
+	case runtime.NativeFunction:
+		if fn, ok := val.Fn.(LuaFunctionVal); ok {
+			return fn.Raw(), nil
+		}
+	case map[string]interface{}:
+		tbl := L.NewTable()
+		for key, item := range val {
+			lv, err := nativeToLua(L, item)
+			if err != nil {
+				return nil, err
+			}
+			tbl.RawSetString(key, lv)
+		}
+		return tbl, nil
+	}
+
+	return nil, fmt.Errorf("Unsupported data type %T", v)
+}
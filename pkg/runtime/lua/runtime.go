@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"sync"
 
 	"github.com/patrixr/glue/pkg/runtime"
 	lua "github.com/yuin/gopher-lua"
@@ -11,6 +12,9 @@ import (
 
 type LuaRuntime struct {
 	L *lua.LState
+
+	// serializes the calls made while executing a blueprint, which may run actions concurrently
+	callMu sync.Mutex
 }
 
 func NewLuaRuntime() runtime.Runtime {
@@ -162,6 +166,10 @@ func (luaruntime *LuaRuntime) RaiseError(format string, args ...interface{}) {
 	luaruntime.L.RaiseError(format, args...)
 }
 
+func (luaruntime *LuaRuntime) Where() string {
+	return strings.TrimSuffix(luaruntime.L.Where(1), ":")
+}
+
 func (luaruntime *LuaRuntime) Lang() string {
 	return "lua"
 }
@@ -176,46 +184,36 @@ func (luaruntime *LuaRuntime) SetFunction(
 	var impl lua.LGFunction = func(L *lua.LState) int {
 		var values []runtime.RTValue = []runtime.RTValue{}
 
-		// Based on the expected arguments, we collect all the values form the input
-		for i, arg := range args {
-			idx := i + 1
+		idx := 1
 
-			if arg.Type.Is(runtime.STRING) {
-				values = append(values, NewString(lua.LString(L.CheckString(idx))))
+		// Based on the expected arguments, we collect all the values form the input
+		for _, arg := range args {
+			if arg.Optional && !luaTypeMatches(L.Get(idx), arg.Type) {
+				// optional arguments can be omitted, the following arguments are shifted
+				values = append(values, Nil())
 				continue
 			}
 
-			if arg.Type.Is(runtime.NUMBER) {
+			switch {
+			case arg.Type.Is(runtime.STRING):
+				values = append(values, NewString(lua.LString(L.CheckString(idx))))
+			case arg.Type.Is(runtime.NUMBER):
 				values = append(values, NewNumber(L.CheckNumber(idx)))
-				continue
-			}
-
-			if arg.Type.Is(runtime.DICT) {
+			case arg.Type.Is(runtime.DICT):
 				values = append(values, NewDict(L.CheckTable(idx)))
-				continue
-			}
-
-			if arg.Type.Is(runtime.ARRAY) {
+			case arg.Type.Is(runtime.ARRAY):
 				values = append(values, NewArray(L.CheckTable(idx)))
-				continue
-			}
-
-			if arg.Type.Is(runtime.FUNC) {
+			case arg.Type.Is(runtime.FUNC):
 				values = append(values, NewFunc(L.CheckFunction(idx)))
-				continue
-			}
-
-			if arg.Type.Is(runtime.BOOL) {
+			case arg.Type.Is(runtime.BOOL):
 				values = append(values, NewBool(lua.LBool(L.CheckBool(idx))))
-				continue
-			}
-
-			if arg.Type.Is(runtime.ANY) {
+			case arg.Type.Is(runtime.ANY):
 				values = append(values, AnyValue(L.CheckAny(idx)))
-				continue
+			default:
+				luaruntime.RaiseError("Unsupported type %s for argument %s", runtime.TypeName(arg.Type), arg.Name)
 			}
 
-			luaruntime.RaiseError("Unsupported type %s for argument %s", runtime.TypeName(arg.Type), arg.Name)
+			idx++
 		}
 
 		val := fn(luaruntime, runtime.NewArguments(luaruntime, values))
@@ -235,6 +233,23 @@ func (luaruntime *LuaRuntime) SetFunction(
 	return err
 }
 
+// (internal)
+func luaTypeMatches(lv lua.LValue, typ runtime.Type) bool {
+	switch {
+	case typ.Is(runtime.STRING):
+		return lv.Type() == lua.LTString
+	case typ.Is(runtime.NUMBER):
+		return lv.Type() == lua.LTNumber
+	case typ.Is(runtime.DICT), typ.Is(runtime.ARRAY):
+		return lv.Type() == lua.LTTable
+	case typ.Is(runtime.FUNC):
+		return lv.Type() == lua.LTFunction
+	case typ.Is(runtime.BOOL):
+		return lv.Type() == lua.LTBool
+	}
+	return lv.Type() != lua.LTNil
+}
+
 func (luaruntime *LuaRuntime) InvokeFunction(fn runtime.RTFunction, params ...runtime.RTValue) error {
 	return luaruntime.invoke(fn, false, params...)
 }
@@ -268,6 +283,33 @@ func (luaruntime *LuaRuntime) invoke(fn runtime.RTFunction, safe bool, params ..
 	}, vals...)
 }
 
+func (luaruntime *LuaRuntime) CallFunction(fn runtime.RTFunction, params ...runtime.RTValue) (interface{}, error) {
+	luafn, ok := fn.(LuaFunctionVal)
+
+	if !ok {
+		return nil, fmt.Errorf("Failed to invoke function variable: %s", fn.String())
+	}
+
+	luaruntime.callMu.Lock()
+	defer luaruntime.callMu.Unlock()
+
+	L := luaruntime.L
+	vals := make([]lua.LValue, len(params))
+
+	for i, param := range params {
+		vals[i] = luaruntime.getRawLuaValue(param)
+	}
+
+	if err := L.CallByParam(lua.P{Fn: luafn.Raw(), NRet: 1, Protect: true}, vals...); err != nil {
+		return nil, err
+	}
+
+	ret := L.Get(-1)
+	L.Pop(1)
+
+	return luaToNative(ret)
+}
+
 func (luaruntime *LuaRuntime) SetGlobal(path string, val runtime.RTValue) ([]string, error) {
 	L := luaruntime.L
 	var value lua.LValue = nil
@@ -23,7 +23,13 @@ type Runtime interface {
 	EnsureArray(v RTValue) RTArray
 	InvokeFunction(fn RTFunction, params ...RTValue) error
 	InvokeFunctionSafe(fn RTFunction, params ...RTValue) error
+	// Invokes the function in protected mode, returning its first result as native data
+	CallFunction(fn RTFunction, params ...RTValue) (interface{}, error)
 	SetGlobal(name string, val RTValue) ([]string, error)
+	// Describes the location of the script code calling the current function, e.g "glue.lua:12"
+	Where() string
+	ToNative(v RTValue) (interface{}, error)
+	FromNative(v interface{}, typ Type) (RTValue, error)
 	SetFunction(
 		name string,
 		desc string,
@@ -33,7 +39,8 @@ type Runtime interface {
 }
 
 type ArgDef struct {
-	Type Type
-	Name string
-	Desc string
+	Type     Type
+	Name     string
+	Desc     string
+	Optional bool
 }
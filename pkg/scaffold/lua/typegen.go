@@ -208,7 +208,11 @@ func (funcAnnotation *LuaFuncAnnotation) Render() string {
 	builder.WriteString("---\n")
 
 	for _, arg := range funcAnnotation.plug.Args {
-		builder.WriteString(fmt.Sprintf("---@param %s %s %s\n", arg.Name, runtime.TypeName(arg.Type), arg.Desc))
+		optstr := ""
+		if arg.Optional {
+			optstr = "?"
+		}
+		builder.WriteString(fmt.Sprintf("---@param %s%s %s %s\n", arg.Name, optstr, runtime.TypeName(arg.Type), arg.Desc))
 	}
 
 	builder.WriteString("---\n")